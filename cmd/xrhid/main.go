@@ -0,0 +1,352 @@
+// Command xrhid decodes, encodes, validates and generates X-Rh-Identity headers.
+//
+// Usage:
+//
+//	xrhid decode [header]             pretty-print the JSON of a header as sent
+//	xrhid encode [file]               encode a JSON identity into a header
+//	xrhid validate [-strict] [header] check a header against the base policy
+//	xrhid validate -schema [header]   check a header against the JSON Schema
+//	xrhid generate [flags]            generate a sample identity as JSON
+//	xrhid curl [flags] URL            print a curl command with a sample identity
+//	xrhid schema                      print the JSON Schema of identities
+//
+// Headers and files are read from standard input when omitted or "-".
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/internal/fixtures"
+)
+
+const usage = `usage: xrhid <command> [flags] [args]
+
+commands:
+  decode [header]              pretty-print the JSON of a header as sent
+  encode [file]                encode a JSON identity into a header
+  validate [-strict] [header]  check a header against the base policy
+  validate -schema [header]    check a header against the JSON Schema
+  generate [flags]             generate a sample identity as JSON
+  curl [flags] URL             print a curl command with a sample identity
+  schema                       print the JSON Schema of identities
+
+Headers and files are read from standard input when omitted or "-".
+`
+
+// errUsage is returned for invalid command line arguments
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command and returns the process exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	commands := map[string]func(args []string, stdin io.Reader, stdout, stderr io.Writer) error{
+		"decode":   decode,
+		"encode":   encode,
+		"validate": validate,
+		"generate": generate,
+		"curl":     curl,
+		"schema":   schema,
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	if err := cmd(args[1:], stdin, stdout, stderr); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "xrhid %s: %s\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("xrhid "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parse parses flags and returns the only optional positional argument
+func parse(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	switch fs.NArg() {
+	case 0:
+		return "-", nil
+	case 1:
+		return fs.Arg(0), nil
+	default:
+		fmt.Fprintf(fs.Output(), "too many arguments\n")
+		return "", errUsage
+	}
+}
+
+// input returns the argument or standard input when the argument is "-"
+func input(arg string, stdin io.Reader) (string, error) {
+	if arg != "-" {
+		return arg, nil
+	}
+	buf, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// header returns the header value with an optional "X-Rh-Identity:" prefix removed
+func header(arg string, stdin io.Reader) (string, error) {
+	h, err := input(arg, stdin)
+	if err != nil {
+		return "", err
+	}
+	h = strings.TrimSpace(h)
+	if name, value, ok := strings.Cut(h, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "x-rh-identity") {
+		h = strings.TrimSpace(value)
+	}
+	return h, nil
+}
+
+// debugOptions decode headers leniently, the tool is used to investigate bad headers
+var debugOptions = identity.DecodeOptions{
+	Base64Encodings: identity.TolerantBase64Encodings,
+	StripWhitespace: true,
+}
+
+func decode(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	arg, err := parse(newFlagSet("decode", stderr), args)
+	if err != nil {
+		return err
+	}
+	h, err := header(arg, stdin)
+	if err != nil {
+		return err
+	}
+
+	// decoding reports errors, the JSON is printed as sent without the normalization
+	// performed by DecodeIdentity
+	if _, err := debugOptions.DecodeIdentity(h); err != nil {
+		return err
+	}
+	raw, _, err := debugOptions.DecodeBase64(h)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err = stdout.Write(buf.Bytes())
+	return err
+}
+
+func encode(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	arg, err := parse(newFlagSet("encode", stderr), args)
+	if err != nil {
+		return err
+	}
+
+	var buf []byte
+	if arg == "-" {
+		buf, err = io.ReadAll(stdin)
+	} else {
+		buf, err = os.ReadFile(arg)
+	}
+	if err != nil {
+		return err
+	}
+
+	var id identity.XRHID
+	if err := json.Unmarshal(buf, &id); err != nil {
+		return fmt.Errorf("%w: %s", identity.ErrUnmarshalIdentity, err)
+	}
+	raw, err := json.Marshal(id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, base64.StdEncoding.EncodeToString(raw))
+	return nil
+}
+
+func validate(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("validate", stderr)
+	strict := fs.Bool("strict", false, "reject unknown fields, duplicate keys and trailing data")
+	withSchema := fs.Bool("schema", false, "report all JSON Schema violations instead of the first base policy error")
+	arg, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	h, err := header(arg, stdin)
+	if err != nil {
+		return err
+	}
+
+	if *withSchema {
+		raw, _, err := debugOptions.DecodeBase64(h)
+		if err != nil {
+			return err
+		}
+		var errs identity.SchemaErrors
+		if err := identity.ValidateSchema(raw); errors.As(err, &errs) {
+			for _, se := range errs {
+				fmt.Fprintln(stdout, se)
+			}
+			return fmt.Errorf("%d schema violation(s)", len(errs))
+		} else if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "valid")
+		return nil
+	}
+
+	opts := identity.DecodeOptions{Strict: *strict}
+	if _, err := opts.DecodeAndCheckIdentity(h); err != nil {
+		return fmt.Errorf("%s (%s)", err, identity.ErrorCode(err))
+	}
+	fmt.Fprintln(stdout, "valid")
+	return nil
+}
+
+// sampleFlags registers flags of generated identities
+type sampleFlags struct {
+	idType   *string
+	orgID    *string
+	username *string
+	orgAdmin *bool
+}
+
+func newSampleFlags(fs *flag.FlagSet) sampleFlags {
+	return sampleFlags{
+		idType:   fs.String("type", string(identity.TypeUser), "identity type: User, System, Associate, X509 or ServiceAccount"),
+		orgID:    fs.String("org-id", fixtures.DefaultOrgID, "org_id of the identity"),
+		username: fs.String("username", "", "username of User and ServiceAccount identities"),
+		orgAdmin: fs.Bool("org-admin", false, "set is_org_admin of User identities"),
+	}
+}
+
+func (f sampleFlags) build() (identity.XRHID, error) {
+	id, err := fixtures.Sample(identity.IdentityType(*f.idType), *f.orgID, *f.username)
+	if err != nil {
+		return id, err
+	}
+	if id.Identity.User != nil {
+		id.Identity.User.OrgAdmin = *f.orgAdmin
+	}
+	return id, nil
+}
+
+// encodeHeader returns the identity encoded for the X-Rh-Identity header
+func encodeHeader(id identity.XRHID) (string, error) {
+	raw, err := json.Marshal(id)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+func generate(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("generate", stderr)
+	sample := newSampleFlags(fs)
+	asHeader := fs.Bool("header", false, "print the encoded header instead of JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintf(stderr, "too many arguments\n")
+		return errUsage
+	}
+
+	id, err := sample.build()
+	if err != nil {
+		return err
+	}
+	if *asHeader {
+		h, err := encodeHeader(id)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, h)
+		return nil
+	}
+	return writeJSON(stdout, id)
+}
+
+func curl(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("curl", stderr)
+	sample := newSampleFlags(fs)
+	method := fs.String("X", "GET", "HTTP method")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintf(stderr, "expected exactly one URL\n")
+		return errUsage
+	}
+
+	id, err := sample.build()
+	if err != nil {
+		return err
+	}
+	h, err := encodeHeader(id)
+	if err != nil {
+		return err
+	}
+
+	cmd := "curl"
+	if *method != "GET" {
+		cmd += " -X " + shellQuote(*method)
+	}
+	fmt.Fprintf(stdout, "%s -H %s %s\n", cmd, shellQuote("X-Rh-Identity: "+h), shellQuote(fs.Arg(0)))
+	return nil
+}
+
+func schema(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("schema", stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintf(stderr, "too many arguments\n")
+		return errUsage
+	}
+	_, err := stdout.Write(identity.JSONSchema())
+	return err
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeJSON(w io.Writer, v any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("xrhid", func() {
+	var stdout, stderr bytes.Buffer
+
+	exec := func(stdin string, args ...string) int {
+		stdout.Reset()
+		stderr.Reset()
+		return run(args, strings.NewReader(stdin), &stdout, &stderr)
+	}
+
+	It("should print usage", func() {
+		Expect(exec("")).To(Equal(2))
+		Expect(stderr.String()).To(HavePrefix("usage: xrhid"))
+		Expect(exec("", "bogus")).To(Equal(2))
+		Expect(stderr.String()).To(HavePrefix(`unknown command "bogus"`))
+	})
+
+	It("should decode headers from arguments and stdin", func() {
+		header := identitytest.User().Username("jane").Header()
+		Expect(exec("", "decode", header)).To(Equal(0))
+		Expect(stdout.String()).To(ContainSubstring(`"username": "jane"`))
+
+		Expect(exec("X-Rh-Identity: "+header+"\n", "decode")).To(Equal(0))
+		Expect(stdout.String()).To(ContainSubstring(`"username": "jane"`))
+	})
+
+	It("should print the JSON as sent", func() {
+		header := base64.StdEncoding.EncodeToString([]byte(`{"identity":{"type":"User","internal":{"org_id":"1"}}}`))
+		Expect(exec("", "decode", header)).To(Equal(0))
+		Expect(stdout.String()).To(Equal(`{
+  "identity": {
+    "type": "User",
+    "internal": {
+      "org_id": "1"
+    }
+  }
+}
+`))
+	})
+
+	It("should report decoding errors", func() {
+		Expect(exec("", "decode", "!!!")).To(Equal(1))
+		Expect(stderr.String()).To(HavePrefix("xrhid decode: unable to b64 decode x-rh-identity header"))
+	})
+
+	It("should encode JSON and keep unknown fields", func() {
+		Expect(exec(`{"identity": {"type": "User", "org_id": "1", "new": 1}}`, "encode")).To(Equal(0))
+		id, err := identity.DecodeAndCheckIdentity(strings.TrimSpace(stdout.String()))
+		Expect(err).To(BeNil())
+		Expect(string(id.Extra["/identity/new"])).To(Equal("1"))
+	})
+
+	It("should validate against the base policy", func() {
+		Expect(exec("", "validate", identitytest.System().Header())).To(Equal(0))
+		Expect(stdout.String()).To(Equal("valid\n"))
+
+		Expect(exec("", "validate", identitytest.User().OrgID("").Header())).To(Equal(1))
+		Expect(stderr.String()).To(Equal("xrhid validate: x-rh-identity header has an invalid or missing org_id (invalid_org_id)\n"))
+
+		header := identitytest.User().Modify(func(id *identity.XRHID) {
+			id.Extra = map[string]json.RawMessage{"/identity/new": json.RawMessage("1")}
+		}).Header()
+		Expect(exec("", "validate", header)).To(Equal(0))
+		Expect(exec("", "validate", "-strict", header)).To(Equal(1))
+	})
+
+	It("should report all schema violations", func() {
+		Expect(exec("", "validate", "-schema", identitytest.X509().Header())).To(Equal(0))
+		Expect(stdout.String()).To(Equal("valid\n"))
+
+		header := base64.StdEncoding.EncodeToString([]byte(`{"identity": {"type": "User", "org_id": 1}, "entitlements": []}`))
+		Expect(exec("", "validate", "-schema", header)).To(Equal(1))
+		Expect(stdout.String()).To(Equal("entitlements: expected object or null, got array\n" +
+			"identity.org_id: expected string, got number\n" +
+			"identity: does not match any of: identity with type and org_id, identity with type and internal.org_id, Associate or X509 identity without account_number\n"))
+		Expect(stderr.String()).To(Equal("xrhid validate: 3 schema violation(s)\n"))
+	})
+
+	It("should print the schema", func() {
+		Expect(exec("", "schema")).To(Equal(0))
+		Expect(stdout.Bytes()).To(Equal(identity.JSONSchema()))
+	})
+
+	It("should generate sample identities", func() {
+		for _, t := range identity.IdentityTypes {
+			Expect(exec("", "generate", "-type", string(t), "-header")).To(Equal(0))
+			id, err := identity.DecodeAndCheckIdentity(strings.TrimSpace(stdout.String()))
+			Expect(err).To(BeNil())
+			Expect(id.Identity.IdentityType()).To(Equal(t))
+		}
+
+		Expect(exec("", "generate", "-org-id", "42", "-org-admin")).To(Equal(0))
+		Expect(stdout.String()).To(ContainSubstring(`"org_id": "42"`))
+		Expect(stdout.String()).To(ContainSubstring(`"is_org_admin": true`))
+
+		Expect(exec("", "generate", "-type", "Robot")).To(Equal(1))
+	})
+
+	It("should print curl commands", func() {
+		Expect(exec("", "curl", "-X", "POST", "http://localhost:8000/api/app/v1/items")).To(Equal(0))
+		Expect(stdout.String()).To(Equal("curl -X 'POST' -H 'X-Rh-Identity: " + identitytest.User().Header() + "' 'http://localhost:8000/api/app/v1/items'\n"))
+
+		Expect(exec("", "curl")).To(Equal(2))
+	})
+})
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestXRHID(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "XRHID Suite")
+}
@@ -0,0 +1,77 @@
+package identity
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// Base64Encoding is a variant of base64 encoding of the X-Rh-Identity header.
+type Base64Encoding int
+
+const (
+	// Base64Std is the standard padded encoding, the only one accepted by default.
+	Base64Std Base64Encoding = iota
+	// Base64RawStd is the standard encoding without padding.
+	Base64RawStd
+	// Base64URL is the padded URL-safe encoding.
+	Base64URL
+	// Base64RawURL is the URL-safe encoding without padding.
+	Base64RawURL
+)
+
+// TolerantBase64Encodings are all supported encodings in the order they are tried.
+var TolerantBase64Encodings = []Base64Encoding{Base64Std, Base64RawStd, Base64URL, Base64RawURL}
+
+func (e Base64Encoding) String() string {
+	switch e {
+	case Base64Std:
+		return "std"
+	case Base64RawStd:
+		return "raw-std"
+	case Base64URL:
+		return "url"
+	case Base64RawURL:
+		return "raw-url"
+	default:
+		return "unknown"
+	}
+}
+
+func (e Base64Encoding) encoding() *base64.Encoding {
+	switch e {
+	case Base64RawStd:
+		return base64.RawStdEncoding
+	case Base64URL:
+		return base64.URLEncoding
+	case Base64RawURL:
+		return base64.RawURLEncoding
+	default:
+		return base64.StdEncoding
+	}
+}
+
+var whitespaceRemover = strings.NewReplacer(" ", "", "\t", "", "\n", "", "\r", "")
+
+// DecodeBase64 decodes the header trying the configured encodings in order and
+// returns the decoded bytes and the encoding which succeeded. ErrDecodeIdentity is
+// returned when no encoding succeeded.
+func (o DecodeOptions) DecodeBase64(header string) ([]byte, Base64Encoding, error) {
+	if o.StripWhitespace {
+		header = whitespaceRemover.Replace(header)
+	}
+
+	encodings := o.Base64Encodings
+	if len(encodings) == 0 {
+		encodings = TolerantBase64Encodings[:1]
+	}
+
+	for _, enc := range encodings {
+		if data, err := enc.encoding().DecodeString(header); err == nil {
+			if enc != Base64Std && o.OnNonStandardBase64 != nil {
+				o.OnNonStandardBase64(header, enc)
+			}
+			return data, enc, nil
+		}
+	}
+	return nil, Base64Std, ErrDecodeIdentity
+}
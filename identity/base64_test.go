@@ -0,0 +1,70 @@
+package identity_test
+
+import (
+	"encoding/base64"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Base64 encodings", func() {
+	// the JSON contains characters which differ between std and URL encodings
+	json := `{"identity": {"type": "User", "org_id": "1", "user": {"username": "a?>b~"}}}`
+
+	encoded := map[identity.Base64Encoding]string{
+		identity.Base64Std:    base64.StdEncoding.EncodeToString([]byte(json)),
+		identity.Base64RawStd: base64.RawStdEncoding.EncodeToString([]byte(json)),
+		identity.Base64URL:    base64.URLEncoding.EncodeToString([]byte(json)),
+		identity.Base64RawURL: base64.RawURLEncoding.EncodeToString([]byte(json)),
+	}
+
+	It("should only accept standard encoding by default", func() {
+		for enc, header := range encoded {
+			_, err := identity.DecodeAndCheckIdentity(header)
+			if enc == identity.Base64Std {
+				Expect(err).To(BeNil())
+			} else {
+				Expect(err).To(MatchError(identity.ErrDecodeIdentity))
+			}
+		}
+	})
+
+	It("should accept all variants and report them", func() {
+		var reported []identity.Base64Encoding
+		opts := identity.DecodeOptions{
+			Base64Encodings: identity.TolerantBase64Encodings,
+			OnNonStandardBase64: func(_ string, enc identity.Base64Encoding) {
+				reported = append(reported, enc)
+			},
+		}
+
+		for _, enc := range identity.TolerantBase64Encodings {
+			id, err := opts.DecodeAndCheckIdentity(encoded[enc])
+			Expect(err).To(BeNil())
+			Expect(id.Identity.User.Username).To(Equal("a?>b~"))
+
+			_, used, err := opts.DecodeBase64(encoded[enc])
+			Expect(err).To(BeNil())
+			Expect(used).To(Equal(enc))
+		}
+		Expect(reported).To(ContainElements(identity.Base64RawStd, identity.Base64URL, identity.Base64RawURL))
+		Expect(reported).ToNot(ContainElement(identity.Base64Std))
+	})
+
+	It("should strip whitespace when configured", func() {
+		header := encoded[identity.Base64Std]
+		header = header[:10] + " \t" + header[10:]
+
+		_, err := identity.DecodeAndCheckIdentity(header)
+		Expect(err).To(MatchError(identity.ErrDecodeIdentity))
+
+		_, err = identity.DecodeOptions{StripWhitespace: true}.DecodeAndCheckIdentity(header)
+		Expect(err).To(BeNil())
+	})
+
+	It("should describe encodings", func() {
+		Expect(identity.Base64RawURL.String()).To(Equal("raw-url"))
+	})
+})
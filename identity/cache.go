@@ -0,0 +1,197 @@
+package identity
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// CacheConfig configures the decoded identity cache.
+type CacheConfig struct {
+	// MaxEntries is the maximum number of cached identities, 4096 when zero.
+	MaxEntries int
+
+	// MaxBytes is the approximate upper bound of memory used by cached entries, the
+	// estimate is based on the raw header length. Zero means no byte limit.
+	MaxBytes int
+
+	// TTL is the maximum age of a cached entry, zero means entries never expire and
+	// are only evicted when the cache is full.
+	TTL time.Duration
+}
+
+// CacheStats are cache counters.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int
+}
+
+// Cache is a bounded LRU cache of decoded and checked identities keyed by the raw
+// header string. Only successfully decoded identities are cached. Cached values are
+// copied on every hit so callers may modify them. Cache is safe for concurrent use.
+type Cache struct {
+	cfg   CacheConfig
+	now   func() time.Time
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	stats CacheStats
+}
+
+type cacheEntry struct {
+	header  string
+	id      XRHID
+	size    int
+	expires time.Time
+}
+
+// entryOverhead is the estimated per-entry size of the list element, map entry and
+// the decoded identity struct
+const entryOverhead = 512
+
+// NewCache returns a new identity cache.
+func NewCache(cfg CacheConfig) *Cache {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 4096
+	}
+	return &Cache{
+		cfg:   cfg,
+		now:   time.Now,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// DecodeAndCheckIdentity works like the package-level function of the same name but
+// returns cached values for headers which were successfully decoded before.
+func (c *Cache) DecodeAndCheckIdentity(header string) (XRHID, error) {
+	return DecodeOptions{Cache: c}.DecodeAndCheckIdentity(header)
+}
+
+// DecodeIdentityCtx works like the package-level function of the same name but uses
+// the cache.
+func (c *Cache) DecodeIdentityCtx(ctx context.Context, header string) (context.Context, error) {
+	return DecodeOptions{Cache: c}.DecodeIdentityCtx(ctx, header)
+}
+
+// Stats returns a snapshot of cache counters.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Purge removes all entries from the cache, counters are kept.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.stats.Entries = 0
+	c.stats.Bytes = 0
+}
+
+func (c *Cache) get(header string) (XRHID, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[header]
+	if !ok {
+		c.stats.Misses++
+		return XRHID{}, false
+	}
+
+	e := el.Value.(*cacheEntry)
+	if c.cfg.TTL > 0 && c.now().After(e.expires) {
+		c.remove(el)
+		c.stats.Misses++
+		return XRHID{}, false
+	}
+
+	c.ll.MoveToFront(el)
+	c.stats.Hits++
+	return e.id.clone(), true
+}
+
+func (c *Cache) add(header string, id XRHID) {
+	size := 2*len(header) + entryOverhead
+	if c.cfg.MaxBytes > 0 && size > c.cfg.MaxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[header]; ok {
+		c.remove(el)
+	}
+
+	e := &cacheEntry{header: header, id: id.clone(), size: size}
+	if c.cfg.TTL > 0 {
+		e.expires = c.now().Add(c.cfg.TTL)
+	}
+	c.items[header] = c.ll.PushFront(e)
+	c.stats.Entries++
+	c.stats.Bytes += size
+
+	for c.stats.Entries > c.cfg.MaxEntries || (c.cfg.MaxBytes > 0 && c.stats.Bytes > c.cfg.MaxBytes) {
+		c.remove(c.ll.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove must be called with the lock held
+func (c *Cache) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*cacheEntry)
+	delete(c.items, e.header)
+	c.stats.Entries--
+	c.stats.Bytes -= e.size
+}
+
+// WithCache sets the cache used by the middleware to decode identities.
+func WithCache(cache *Cache) Option {
+	return func(m *middleware) {
+		m.decode.Cache = cache
+	}
+}
+
+// clone returns a deep copy of the identity
+func (x XRHID) clone() XRHID {
+	c := x
+	c.Extra = cloneExtra(x.Extra)
+	id := &c.Identity
+	if id.User != nil {
+		u := *id.User
+		id.User = &u
+	}
+	if id.System != nil {
+		s := *id.System
+		id.System = &s
+	}
+	if id.Associate != nil {
+		a := *id.Associate
+		if a.Role != nil {
+			a.Role = append([]string(nil), a.Role...)
+		}
+		id.Associate = &a
+	}
+	if id.X509 != nil {
+		x := *id.X509
+		id.X509 = &x
+	}
+	if id.ServiceAccount != nil {
+		sa := *id.ServiceAccount
+		id.ServiceAccount = &sa
+	}
+	if x.Entitlements != nil {
+		c.Entitlements = make(map[string]ServiceDetails, len(x.Entitlements))
+		for k, v := range x.Entitlements {
+			c.Entitlements[k] = v
+		}
+	}
+	return c
+}
@@ -0,0 +1,183 @@
+package identity_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache", func() {
+	It("should count hits and misses", func() {
+		cache := identity.NewCache(identity.CacheConfig{})
+		header := getBase64(exampleHeader)
+
+		for i := 0; i < 3; i++ {
+			id, err := cache.DecodeAndCheckIdentity(header)
+			Expect(err).To(BeNil())
+			Expect(id.Identity.OrgID).To(Equal("1979710"))
+		}
+		Expect(cache.Stats()).To(Equal(identity.CacheStats{Hits: 2, Misses: 1, Entries: 1, Bytes: 2*len(header) + 512}))
+	})
+
+	It("should not cache errors", func() {
+		cache := identity.NewCache(identity.CacheConfig{})
+		_, err := cache.DecodeAndCheckIdentity("=invalid")
+		Expect(err).To(MatchError(identity.ErrDecodeIdentity))
+		Expect(cache.Stats().Entries).To(Equal(0))
+	})
+
+	It("should return copies of cached values", func() {
+		cache := identity.NewCache(identity.CacheConfig{})
+		header := identitytest.Associate().Roles("admin").Header()
+
+		first, _ := cache.DecodeAndCheckIdentity(header)
+		first.Identity.Associate.Role[0] = "changed"
+		first.Entitlements["insights"] = identity.ServiceDetails{}
+
+		second, _ := cache.DecodeAndCheckIdentity(header)
+		Expect(second.Identity.Associate.Role).To(Equal([]string{"admin"}))
+		Expect(second.Entitlements["insights"].IsEntitled).To(BeTrue())
+	})
+
+	It("should evict least recently used entries", func() {
+		cache := identity.NewCache(identity.CacheConfig{MaxEntries: 2})
+		a, b, c := identitytest.User().Header(), identitytest.System().Header(), identitytest.X509().Header()
+
+		_, _ = cache.DecodeAndCheckIdentity(a)
+		_, _ = cache.DecodeAndCheckIdentity(b)
+		_, _ = cache.DecodeAndCheckIdentity(a)
+		_, _ = cache.DecodeAndCheckIdentity(c)
+		Expect(cache.Stats().Evictions).To(Equal(uint64(1)))
+
+		_, _ = cache.DecodeAndCheckIdentity(a)
+		Expect(cache.Stats().Hits).To(Equal(uint64(2)))
+		_, _ = cache.DecodeAndCheckIdentity(b)
+		Expect(cache.Stats().Misses).To(Equal(uint64(4)))
+	})
+
+	It("should respect the byte limit", func() {
+		header := identitytest.User().Header()
+		cache := identity.NewCache(identity.CacheConfig{MaxBytes: 2*len(header) + 512})
+
+		_, _ = cache.DecodeAndCheckIdentity(header)
+		_, _ = cache.DecodeAndCheckIdentity(identitytest.ServiceAccount().Header())
+		Expect(cache.Stats().Entries).To(Equal(1))
+		Expect(cache.Stats().Bytes).To(BeNumerically("<=", 2*len(header)+512))
+	})
+
+	It("should expire entries", func() {
+		cache := identity.NewCache(identity.CacheConfig{TTL: time.Millisecond})
+		header := identitytest.User().Header()
+
+		_, _ = cache.DecodeAndCheckIdentity(header)
+		time.Sleep(5 * time.Millisecond)
+		_, _ = cache.DecodeAndCheckIdentity(header)
+		Expect(cache.Stats().Misses).To(Equal(uint64(2)))
+		Expect(cache.Stats().Entries).To(Equal(1))
+	})
+
+	It("should be used by the middleware", func() {
+		cache := identity.NewCache(identity.CacheConfig{})
+		handler := identity.EnforceIdentityWithOptions(identity.WithCache(cache))(GetTestHandler(true))
+		for i := 0; i < 2; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), identitytest.User().Request("GET", "/"))
+		}
+		Expect(cache.Stats().Hits).To(Equal(uint64(1)))
+	})
+})
+
+func BenchmarkDecodeIdentityCtx(b *testing.B) {
+	header := getBase64(exampleHeader)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := identity.DecodeIdentityCtx(ctx, header); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// unknownFieldsHeader has unknown fields on several levels of the identity
+var unknownFieldsHeader = `{"gateway": {"version": 2}, "identity": {"type": "User", "org_id": "1979710",
+	"internal": {"org_id": "1979710", "region": "us-east-1"}, "user": {"username": "jdoe", "source_id": "a1"}},
+	"entitlements": {"insights": {"is_entitled": true, "attributes": {"sku": "MCT0000"}}}}`
+
+func BenchmarkDecodeIdentityCtxUnknownFields(b *testing.B) {
+	header := getBase64(unknownFieldsHeader)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := identity.DecodeIdentityCtx(ctx, header); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnmarshalIdentity compares unmarshalling of the modelled fields only with
+// unmarshalling which also collects unknown fields
+func BenchmarkUnmarshalIdentity(b *testing.B) {
+	type fieldsOnly struct {
+		Identity     identity.Identity                  `json:"identity"`
+		Entitlements map[string]identity.ServiceDetails `json:"entitlements"`
+	}
+
+	for _, bc := range []struct {
+		name string
+		doc  string
+		new  func() any
+	}{
+		{"FieldsOnly", exampleHeader, func() any { return &fieldsOnly{} }},
+		{"XRHID", exampleHeader, func() any { return &identity.XRHID{} }},
+		{"XRHIDUnknownFields", unknownFieldsHeader, func() any { return &identity.XRHID{} }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			raw := []byte(bc.doc)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := json.Unmarshal(raw, bc.new()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCacheDecodeIdentityCtx(b *testing.B) {
+	header := getBase64(exampleHeader)
+	ctx := context.Background()
+	cache := identity.NewCache(identity.CacheConfig{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := cache.DecodeIdentityCtx(ctx, header); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCacheDecodeIdentityCtxParallel(b *testing.B) {
+	headers := make([]string, 0, 1000)
+	for i := 0; i < cap(headers); i++ {
+		headers = append(headers, identitytest.User().UserID(strconv.Itoa(i)).Header())
+	}
+	cache := identity.NewCache(identity.CacheConfig{})
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		i := 0
+		for pb.Next() {
+			if _, err := cache.DecodeIdentityCtx(ctx, headers[i%len(headers)]); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+}
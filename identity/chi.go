@@ -0,0 +1,108 @@
+package identity
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Require returns middleware which checks policies against the identity stored in
+// the request context, typically used for per-route requirements:
+//
+//	r.Use(identity.EnforceIdentityWithLogger(ErrorLogFunc))
+//	r.With(identity.Require(identity.OrgAdmin())).Delete("/items/{id}", deleteItem)
+//
+// Requests rejected by a policy are aborted with HTTP code 403 (or 401 when
+// re-authentication is required), requests without identity in the context (e.g.
+// exempted routes) with HTTP code 400. Logging callback and error responder configured
+// on the enforcing middleware are used.
+func Require(policies ...Policy) func(next http.Handler) http.Handler {
+	return enforcePolicies(nil, policies...)
+}
+
+// RouteReport describes identity protection of a single route.
+type RouteReport struct {
+	// Method is the HTTP method of the route.
+	Method string
+
+	// Route is the full routing pattern.
+	Route string
+
+	// Enforced is true when an identity enforcing middleware is in the chain.
+	Enforced bool
+
+	// Optional is true when the OptionalIdentity middleware is in the chain.
+	Optional bool
+
+	// Exemption is the description of the exemption matching the route pattern, if any.
+	Exemption string
+
+	// Policies are descriptions of all policies in the middleware chain in order.
+	Policies []string
+}
+
+// Protected returns true when the identity is enforced and the route is not exempted.
+func (rr RouteReport) Protected() bool {
+	return rr.Enforced && rr.Exemption == ""
+}
+
+// ReportRoutes walks the chi routing tree and returns a report of identity middlewares
+// and policies for each route, sorted by route and method. Only middlewares from this
+// package are recognized, policies checked by custom middleware are not reported.
+func ReportRoutes(routes chi.Routes) ([]RouteReport, error) {
+	var reports []RouteReport
+	probe := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	walkFn := func(method, route string, _ http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		rr := RouteReport{Method: method, Route: route}
+		for _, mw := range middlewares {
+			switch h := mw(probe).(type) {
+			case *enforceHandler:
+				if h.m.optional {
+					rr.Optional = true
+				} else {
+					rr.Enforced = true
+				}
+				if req, err := http.NewRequest(method, route, nil); err == nil {
+					rr.Exemption, _ = h.m.matchExemption(req)
+				}
+				rr.Policies = append(rr.Policies, describePolicies(BasePolicy())...)
+				rr.Policies = append(rr.Policies, describePolicies(h.m.policies...)...)
+			case *policyHandler:
+				rr.Policies = append(rr.Policies, describePolicies(h.policies...)...)
+			case *crossAccessHandler:
+				if h.denies(method) {
+					rr.Policies = append(rr.Policies, describePolicies(DenyCrossAccess())...)
+				}
+			}
+		}
+		reports = append(reports, rr)
+		return nil
+	}
+
+	if err := chi.Walk(routes, walkFn); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reports[i].Route != reports[j].Route {
+			return reports[i].Route < reports[j].Route
+		}
+		return reports[i].Method < reports[j].Method
+	})
+	return reports, nil
+}
+
+func describePolicies(policies ...Policy) []string {
+	names := make([]string, 0, len(policies))
+	for _, p := range policies {
+		if s, ok := p.(fmt.Stringer); ok {
+			names = append(names, s.String())
+		} else {
+			names = append(names, "custom policy")
+		}
+	}
+	return names
+}
@@ -0,0 +1,103 @@
+package identity_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Chi", func() {
+	var router chi.Router
+
+	BeforeEach(func() {
+		ok := func(w http.ResponseWriter, r *http.Request) {}
+		router = chi.NewRouter()
+		router.Use(identity.EnforceIdentityWithOptions(
+			identity.WithPolicies(identity.RequireOrgID()),
+			identity.WithExemptions(identity.ExemptPaths("/healthz"))))
+		router.Get("/healthz", ok)
+		router.Route("/api/app/v1", func(r chi.Router) {
+			r.Get("/items", ok)
+			r.With(identity.Require(identity.OrgAdmin())).Delete("/items/{id}", ok)
+		})
+	})
+
+	Context("With Require", func() {
+		It("should reject requests not matching the route policy", func() {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", "/api/app/v1/items/1", nil)
+			req.Header.Set("x-rh-identity", getBase64(serviceAccountIdentity))
+			router.ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(403))
+			Expect(rr.Body.String()).To(Equal("Forbidden: x-rh-identity user is not an org admin\n"))
+		})
+
+		It("should reject requests without identity before evaluating policies", func() {
+			rr := httptest.NewRecorder()
+			router.With(identity.Require(identity.DenyCrossAccess())).Post("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+			router.ServeHTTP(rr, httptest.NewRequest("POST", "/healthz", nil))
+			Expect(rr.Code).To(Equal(400))
+			Expect(rr.Body.String()).To(Equal("Bad Request: missing x-rh-identity header\n"))
+		})
+
+		It("should reject requests with invalid optional identity", func() {
+			rr := httptest.NewRecorder()
+			handler := identity.OptionalIdentity()(identity.Require(identity.DenyCrossAccess())(GetTestHandler(false)))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("x-rh-identity", "!!!")
+			handler.ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(400))
+			Expect(rr.Body.String()).To(HavePrefix("Bad Request: unable to b64 decode x-rh-identity header"))
+		})
+
+		It("should use the configured responder for exempted requests", func() {
+			rr := httptest.NewRecorder()
+			handler := identity.EnforceIdentityWithOptions(
+				identity.WithErrorResponder(identity.JSONResponder),
+				identity.WithExemptions(identity.ExemptPaths("/x")))(identity.Require(identity.OrgAdmin())(GetTestHandler(false)))
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/x", nil))
+			Expect(rr.Code).To(Equal(400))
+			Expect(rr.Header().Get("Content-Type")).To(HavePrefix("application/json"))
+			Expect(rr.Body.String()).To(MatchJSON(`{"errors":[{"status":"400","code":"missing_identity","detail":"missing x-rh-identity header"}]}`))
+		})
+
+		It("should keep the status of optional identity policy errors", func() {
+			rr := httptest.NewRecorder()
+			handler := identity.OptionalIdentity(identity.WithPolicies(identity.RequireType(identity.TypeUser)))(
+				identity.Require(identity.OrgAdmin())(GetTestHandler(false)))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("x-rh-identity", identitytest.System().Header())
+			handler.ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(403))
+			Expect(rr.Body.String()).To(HavePrefix("Forbidden: "))
+		})
+
+		It("should pass requests matching the route policy", func() {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", "/api/app/v1/items/1", nil)
+			req.Header.Set("x-rh-identity", getBase64(exampleHeader))
+			router.ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(200))
+		})
+	})
+
+	Context("With ReportRoutes", func() {
+		It("should report policies of each route", func() {
+			reports, err := identity.ReportRoutes(router)
+			Expect(err).To(BeNil())
+			Expect(reports).To(Equal([]identity.RouteReport{
+				{Method: "GET", Route: "/api/app/v1/items", Enforced: true, Policies: []string{"BasePolicy", "RequireOrgID"}},
+				{Method: "DELETE", Route: "/api/app/v1/items/{id}", Enforced: true, Policies: []string{"BasePolicy", "RequireOrgID", "OrgAdmin"}},
+				{Method: "GET", Route: "/healthz", Enforced: true, Exemption: "ExemptPaths(/healthz)", Policies: []string{"BasePolicy", "RequireOrgID"}},
+			}))
+			Expect(reports[2].Protected()).To(BeFalse())
+			Expect(reports[0].Protected()).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,123 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+var ErrCrossAccessDenied = errors.New("x-rh-identity cross-account access is not allowed")
+
+// CrossAccessMode configures handling of cross-account identities, which are used by
+// Red Hat support users acting on behalf of customer organizations.
+type CrossAccessMode int
+
+const (
+	// CrossAccessAllow accepts cross-account identities.
+	CrossAccessAllow CrossAccessMode = iota
+
+	// CrossAccessDeny rejects cross-account identities.
+	CrossAccessDeny
+
+	// CrossAccessReadOnly accepts cross-account identities only for safe HTTP methods
+	// (GET, HEAD, OPTIONS and TRACE).
+	CrossAccessReadOnly
+)
+
+func (m CrossAccessMode) String() string {
+	switch m {
+	case CrossAccessAllow:
+		return "allow"
+	case CrossAccessDeny:
+		return "deny"
+	case CrossAccessReadOnly:
+		return "read-only"
+	default:
+		return "unknown"
+	}
+}
+
+// IsCrossAccess returns true when the identity stored in the context is a cross-account
+// identity.
+func IsCrossAccess(ctx context.Context) bool {
+	id, ok := GetIdentityOK(ctx)
+	return ok && id.Identity.Internal.CrossAccess
+}
+
+// TrackCrossAccess returns a copy of the context in which the identity middleware records
+// cross-account identities, and a function reporting whether one was seen. It lets
+// middlewares placed before the identity middleware, such as request loggers, learn
+// about cross-account access after the request was served.
+func TrackCrossAccess(ctx context.Context) (context.Context, func() bool) {
+	seen := new(atomic.Bool)
+	return context.WithValue(ctx, crossAccessKey, seen), seen.Load
+}
+
+// trackCrossAccess records cross-account identity stored in the context for
+// TrackCrossAccess
+func trackCrossAccess(ctx context.Context) {
+	if seen, ok := ctx.Value(crossAccessKey).(*atomic.Bool); ok && IsCrossAccess(ctx) {
+		seen.Store(true)
+	}
+}
+
+// DenyCrossAccess returns a policy that rejects cross-account identities.
+func DenyCrossAccess() Policy {
+	return namedPolicy{
+		name: "DenyCrossAccess",
+		fn: func(id *XRHID) error {
+			if id.Identity.Internal.CrossAccess {
+				return ErrCrossAccessDenied
+			}
+			return nil
+		},
+	}
+}
+
+// EnforceCrossAccess checks cross-account access of the identity stored in the request
+// context according to the mode. The middleware must be placed after
+// EnforceIdentityWithLogger or one of its variants. Rejected requests are aborted with
+// HTTP code 403.
+func EnforceCrossAccess(logger ErrorFunc, mode CrossAccessMode) func(next http.Handler) http.Handler {
+	deny := enforcePolicies(logger, DenyCrossAccess())
+	return func(next http.Handler) http.Handler {
+		return &crossAccessHandler{mode: mode, deny: deny(next), next: next}
+	}
+}
+
+// crossAccessHandler is the handler created by EnforceCrossAccess, it is a named type
+// so the route report can recognize it.
+type crossAccessHandler struct {
+	mode CrossAccessMode
+	deny http.Handler
+	next http.Handler
+}
+
+// denies returns true when cross-account identities are rejected for the method
+func (h *crossAccessHandler) denies(method string) bool {
+	switch h.mode {
+	case CrossAccessAllow:
+		return false
+	case CrossAccessReadOnly:
+		return !safeMethod(method)
+	default:
+		return true
+	}
+}
+
+func (h *crossAccessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.denies(r.Method) {
+		h.deny.ServeHTTP(w, r)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
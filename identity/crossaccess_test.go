@@ -0,0 +1,71 @@
+package identity_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cross-account access", func() {
+	crossAccess := identitytest.User().CrossAccess(true)
+
+	serve := func(mode identity.CrossAccessMode, req *http.Request) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler := identity.EnforceIdentityWithLogger(noopLogger)(identity.EnforceCrossAccess(noopLogger, mode)(GetTestHandler(true)))
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	It("should set the context flag", func() {
+		Expect(identity.IsCrossAccess(crossAccess.Context(context.Background()))).To(BeTrue())
+		Expect(identity.IsCrossAccess(identitytest.User().Context(context.Background()))).To(BeFalse())
+		Expect(identity.IsCrossAccess(context.Background())).To(BeFalse())
+	})
+
+	It("should deny cross-account identities with the policy", func() {
+		id := crossAccess.Build()
+		err := identity.DenyCrossAccess().Check(&id)
+		Expect(errors.Is(err, identity.ErrCrossAccessDenied)).To(BeTrue())
+		Expect(identity.ErrorCode(err)).To(Equal("cross_access_denied"))
+
+		id = identitytest.User().Build()
+		Expect(identity.DenyCrossAccess().Check(&id)).To(Succeed())
+	})
+
+	It("should handle each mode", func() {
+		Expect(serve(identity.CrossAccessAllow, crossAccess.Request("DELETE", "/items/1")).Code).To(Equal(200))
+		Expect(serve(identity.CrossAccessDeny, crossAccess.Request("GET", "/items")).Code).To(Equal(403))
+		Expect(serve(identity.CrossAccessDeny, identitytest.User().Request("DELETE", "/items/1")).Code).To(Equal(200))
+		Expect(serve(identity.CrossAccessReadOnly, crossAccess.Request("GET", "/items")).Code).To(Equal(200))
+		Expect(serve(identity.CrossAccessReadOnly, crossAccess.Request("DELETE", "/items/1")).Code).To(Equal(403))
+	})
+
+	It("should reject requests without identity", func() {
+		rr := httptest.NewRecorder()
+		identity.EnforceCrossAccess(nil, identity.CrossAccessReadOnly)(GetTestHandler(false)).ServeHTTP(rr, httptest.NewRequest("DELETE", "/items/1", nil))
+		Expect(rr.Code).To(Equal(400))
+	})
+
+	It("should be reported per method", func() {
+		ok := func(w http.ResponseWriter, r *http.Request) {}
+		router := chi.NewRouter()
+		router.Use(identity.EnforceIdentityWithLogger(noopLogger))
+		router.Use(identity.EnforceCrossAccess(noopLogger, identity.CrossAccessReadOnly))
+		router.Get("/items", ok)
+		router.Post("/items", ok)
+
+		reports, err := identity.ReportRoutes(router)
+		Expect(err).To(BeNil())
+		Expect(reports[0].Method).To(Equal("GET"))
+		Expect(reports[0].Policies).To(Equal([]string{"BasePolicy"}))
+		Expect(reports[1].Policies).To(Equal([]string{"BasePolicy", "DenyCrossAccess"}))
+	})
+})
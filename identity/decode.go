@@ -0,0 +1,149 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+)
+
+// DecodeOptions configure decoding of identity headers. The zero value decodes
+// identities exactly like the package-level DecodeIdentity function.
+type DecodeOptions struct {
+	// Limits are enforced before and during unmarshalling when set.
+	Limits *Limits
+
+	// Strict rejects unknown fields, duplicate keys and trailing data. By default,
+	// unknown fields are ignored and the last duplicate key wins.
+	Strict bool
+
+	// Base64Encodings are tried in order when decoding the header, only Base64Std is
+	// used when empty. Use TolerantBase64Encodings to accept all variants.
+	Base64Encodings []Base64Encoding
+
+	// StripWhitespace removes spaces, tabs and newlines from the header before decoding.
+	StripWhitespace bool
+
+	// OnNonStandardBase64 is called when the header was decoded with other than the
+	// standard encoding, which is useful to find non-conforming clients. It is not
+	// called for headers found in the cache.
+	OnNonStandardBase64 func(header string, enc Base64Encoding)
+
+	// Cache is used to look up previously decoded and checked identities when set.
+	// A cache must not be shared between differently configured options.
+	Cache *Cache
+
+	// Resolver fills org_id of identities which only carry account_number when set,
+	// for example NewCachedResolver wrapping HTTPResolver. Only the middleware and
+	// DecodeIdentityCtx pass the request context to the resolver, DecodeIdentity and
+	// DecodeAndCheckIdentity use context.Background and cannot be cancelled.
+	Resolver OrgIDResolver
+
+	// ResolveAccountNumber also fills account_number of identities which only carry
+	// org_id, except Associate and X509 identities. Requires Resolver.
+	ResolveAccountNumber bool
+}
+
+// DecodeIdentity returns identity value decoded from a base64 JSON encoded string
+// according to the options. See the package-level function of the same name.
+func (o DecodeOptions) DecodeIdentity(header string) (XRHID, error) {
+	return o.decode(context.Background(), header)
+}
+
+// decode performs DecodeIdentity, the context is passed to the resolver
+func (o DecodeOptions) decode(ctx context.Context, header string) (XRHID, error) {
+	if header == "" {
+		return XRHID{}, ErrMissingIdentity
+	}
+
+	if err := o.Limits.checkEncoded(header); err != nil {
+		return XRHID{}, err
+	}
+
+	idRaw, _, err := o.DecodeBase64(header)
+	if err != nil {
+		return XRHID{}, err
+	}
+
+	if err := o.Limits.checkDecoded(idRaw); err != nil {
+		return XRHID{}, err
+	}
+
+	var id XRHID
+	if o.Strict {
+		err = unmarshalStrict(idRaw, &id)
+	} else {
+		err = unmarshalIdentity(idRaw, &id)
+	}
+	if err != nil {
+		return XRHID{}, fmt.Errorf("%w: %w", ErrUnmarshalIdentity, err)
+	}
+
+	// If org_id is not defined at the top level, use the internal one.
+	// See: https://issues.redhat.com/browse/RHCLOUD-17717
+	if id.Identity.OrgID == "" && id.Identity.Internal.OrgID != "" {
+		id.Identity.OrgID = id.Identity.Internal.OrgID
+	}
+
+	if err := o.resolve(ctx, &id); err != nil {
+		return XRHID{}, err
+	}
+
+	return id, nil
+}
+
+// DecodeAndCheckIdentity returns identity value decoded from a base64 JSON encoded string
+// according to the options and checked by the base policy. See the package-level
+// function of the same name.
+func (o DecodeOptions) DecodeAndCheckIdentity(header string) (XRHID, error) {
+	return o.decodeAndCheck(context.Background(), header)
+}
+
+// decodeAndCheck performs DecodeAndCheckIdentity, the context is passed to the resolver
+func (o DecodeOptions) decodeAndCheck(ctx context.Context, header string) (XRHID, error) {
+	if o.Cache != nil {
+		if err := o.Limits.checkEncoded(header); err != nil {
+			return XRHID{}, err
+		}
+		if id, ok := o.Cache.get(header); ok {
+			return id, nil
+		}
+	}
+
+	id, err := o.decode(ctx, header)
+	if err != nil {
+		return XRHID{}, err
+	}
+
+	err = checkBasePolicy(&id)
+	if err != nil {
+		return XRHID{}, err
+	}
+
+	if o.Cache != nil {
+		o.Cache.add(header, id)
+	}
+	return id, nil
+}
+
+// DecodeIdentityCtx decodes, checks and puts identity raw string and value into
+// existing context according to the options. See the package-level function of
+// the same name.
+func (o DecodeOptions) DecodeIdentityCtx(ctx context.Context, header string) (context.Context, error) {
+	id, err := o.decodeAndCheck(ctx, header)
+	if err != nil {
+		return ctx, err
+	}
+	nc := WithIdentity(ctx, id)
+	nc = WithRawIdentity(nc, header)
+	return nc, nil
+}
+
+// WithDecodeOptions sets options used by the middleware to decode identities.
+func WithDecodeOptions(opts DecodeOptions) Option {
+	return func(m *middleware) {
+		cache := m.decode.Cache
+		m.decode = opts
+		if m.decode.Cache == nil {
+			m.decode.Cache = cache
+		}
+	}
+}
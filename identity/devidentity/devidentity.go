@@ -0,0 +1,155 @@
+/*
+Package devidentity provides a middleware for local development which injects a synthetic
+X-Rh-Identity header into requests without one, so services can be called with plain curl.
+
+The middleware must be explicitly enabled and it refuses to start when a production
+marker environment variable (see ProductionMarkers) is present. It must be placed
+before the identity middleware:
+
+	inject, err := devidentity.New(devidentity.ConfigFromEnv())
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Use(inject)
+	r.Use(identity.EnforceIdentityWithLogger(ErrorLogFunc))
+
+When disabled, the returned middleware passes requests unchanged. The injected identity
+is either loaded from a JSON file or built from the OrgID, Username and Type fields.
+*/
+package devidentity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/internal/fixtures"
+)
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvEnabled  = "DEV_IDENTITY_ENABLED"
+	EnvFile     = "DEV_IDENTITY_FILE"
+	EnvOrgID    = "DEV_IDENTITY_ORG_ID"
+	EnvUsername = "DEV_IDENTITY_USER"
+	EnvType     = "DEV_IDENTITY_TYPE"
+)
+
+// Default values of built identities.
+const (
+	DefaultOrgID         = fixtures.DefaultOrgID
+	DefaultAccountNumber = fixtures.DefaultAccountNumber
+	DefaultUsername      = fixtures.DefaultUsername
+)
+
+// ProductionMarkers are environment variables which are set in deployed environments,
+// the middleware refuses to be enabled when any of them is present.
+var ProductionMarkers = []string{"ACG_CONFIG", "CLOWDER_ENABLED"}
+
+var ErrProduction = errors.New("development identity must not be enabled in production")
+
+// Config configures the development identity injector.
+type Config struct {
+	// Enabled must be true for the middleware to inject identities.
+	Enabled bool
+
+	// File is a path to a JSON identity document, when set the other identity fields
+	// are ignored.
+	File string
+
+	// OrgID is the org_id of the identity, DefaultOrgID when empty.
+	OrgID string
+
+	// Username is the username of User and ServiceAccount identities, DefaultUsername
+	// for users when empty.
+	Username string
+
+	// Type is the identity type, identity.TypeUser when empty.
+	Type identity.IdentityType
+
+	// Logger receives warnings about synthetic identities, messages are logged with the
+	// standard log package when nil.
+	Logger identity.ErrorFunc
+}
+
+// ConfigFromEnv returns configuration from DEV_IDENTITY_* environment variables.
+func ConfigFromEnv() Config {
+	enabled, _ := strconv.ParseBool(os.Getenv(EnvEnabled))
+	return Config{
+		Enabled:  enabled,
+		File:     os.Getenv(EnvFile),
+		OrgID:    os.Getenv(EnvOrgID),
+		Username: os.Getenv(EnvUsername),
+		Type:     identity.IdentityType(os.Getenv(EnvType)),
+	}
+}
+
+// New returns the identity injecting middleware. An error is returned when the
+// middleware is enabled in production or the identity cannot be loaded or is invalid.
+func New(cfg Config) (func(next http.Handler) http.Handler, error) {
+	if !cfg.Enabled {
+		return func(next http.Handler) http.Handler { return next }, nil
+	}
+
+	for _, marker := range ProductionMarkers {
+		if os.Getenv(marker) != "" {
+			return nil, fmt.Errorf("%w: %s is set", ErrProduction, marker)
+		}
+	}
+
+	raw, err := cfg.identity()
+	if err != nil {
+		return nil, err
+	}
+	header := base64.StdEncoding.EncodeToString(raw)
+	if _, err := identity.DecodeAndCheckIdentity(header); err != nil {
+		return nil, fmt.Errorf("invalid development identity: %w", err)
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = func(_ context.Context, rawIdentity, msg string) {
+			log.Printf("%s: %s", msg, rawIdentity)
+		}
+	}
+	logger(context.Background(), header, "WARNING: development identity injector is enabled, never use it in production")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Rh-Identity") == "" {
+				logger(r.Context(), header, "WARNING: request without X-Rh-Identity, using synthetic development identity")
+				r = r.Clone(r.Context())
+				r.Header.Set("X-Rh-Identity", header)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// identity returns JSON of the configured identity
+func (cfg Config) identity() ([]byte, error) {
+	if cfg.File != "" {
+		raw, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read development identity: %w", err)
+		}
+		return raw, nil
+	}
+
+	t := cfg.Type
+	if t == "" {
+		t = identity.TypeUser
+	}
+	id, err := fixtures.Sample(t, cfg.OrgID, cfg.Username)
+	if err != nil {
+		return nil, fmt.Errorf("unknown development identity type %q", cfg.Type)
+	}
+	return json.Marshal(id)
+}
@@ -0,0 +1,13 @@
+package devidentity_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDevIdentity(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DevIdentity Suite")
+}
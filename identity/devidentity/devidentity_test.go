@@ -0,0 +1,126 @@
+package devidentity_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/devidentity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Injector", func() {
+	var (
+		messages []string
+		received identity.XRHID
+		dir      string
+	)
+
+	logger := func(_ context.Context, _, msg string) {
+		messages = append(messages, msg)
+	}
+
+	serve := func(cfg devidentity.Config, req *http.Request) int {
+		cfg.Logger = logger
+		inject, err := devidentity.New(cfg)
+		Expect(err).To(BeNil())
+
+		rr := httptest.NewRecorder()
+		handler := inject(identity.EnforceIdentityWithLogger(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = identity.GetIdentity(r.Context())
+		})))
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	BeforeEach(func() {
+		messages = nil
+		received = identity.XRHID{}
+
+		var err error
+		dir, err = os.MkdirTemp("", "devidentity")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should pass requests when disabled", func() {
+		Expect(serve(devidentity.Config{}, httptest.NewRequest("GET", "/", nil))).To(Equal(400))
+		Expect(messages).To(BeEmpty())
+	})
+
+	It("should inject identity built from flags", func() {
+		cfg := devidentity.Config{Enabled: true, OrgID: "12345", Username: "dev", Type: identity.TypeServiceAccount}
+		Expect(serve(cfg, httptest.NewRequest("GET", "/", nil))).To(Equal(200))
+		Expect(received.Identity.OrgID).To(Equal("12345"))
+		Expect(received.Identity.ServiceAccount.Username).To(Equal("dev"))
+		Expect(messages).To(HaveLen(2))
+		Expect(messages[1]).To(ContainSubstring("synthetic development identity"))
+	})
+
+	It("should build valid identities of all types", func() {
+		for _, t := range identity.IdentityTypes {
+			Expect(serve(devidentity.Config{Enabled: true, Type: t}, httptest.NewRequest("GET", "/", nil))).To(Equal(200))
+			Expect(received.Identity.Type).To(Equal(string(t)))
+			Expect(received.Identity.OrgID).To(Equal(devidentity.DefaultOrgID))
+		}
+	})
+
+	It("should keep existing identity", func() {
+		Expect(serve(devidentity.Config{Enabled: true}, identitytest.User().OrgID("1").Request("GET", "/"))).To(Equal(200))
+		Expect(received.Identity.OrgID).To(Equal("1"))
+		Expect(messages).To(HaveLen(1))
+	})
+
+	It("should load identity from a file", func() {
+		file := filepath.Join(dir, "identity.json")
+		Expect(os.WriteFile(file, identitytest.System().OrgID("777").JSON(), 0o600)).To(Succeed())
+
+		Expect(serve(devidentity.Config{Enabled: true, File: file}, httptest.NewRequest("GET", "/", nil))).To(Equal(200))
+		Expect(received.Identity.IsSystem()).To(BeTrue())
+		Expect(received.Identity.OrgID).To(Equal("777"))
+	})
+
+	It("should reject invalid identities", func() {
+		_, err := devidentity.New(devidentity.Config{Enabled: true, Type: "Robot"})
+		Expect(err).To(MatchError(`unknown development identity type "Robot"`))
+
+		file := filepath.Join(dir, "identity.json")
+		Expect(os.WriteFile(file, []byte(`{"identity": {"type": "User"}}`), 0o600)).To(Succeed())
+		_, err = devidentity.New(devidentity.Config{Enabled: true, File: file})
+		Expect(errors.Is(err, identity.ErrInvalidOrgIdIdentity)).To(BeTrue())
+	})
+
+	It("should refuse to run in production", func() {
+		os.Setenv("CLOWDER_ENABLED", "true")
+		defer os.Unsetenv("CLOWDER_ENABLED")
+
+		_, err := devidentity.New(devidentity.Config{Enabled: true})
+		Expect(errors.Is(err, devidentity.ErrProduction)).To(BeTrue())
+
+		_, err = devidentity.New(devidentity.Config{})
+		Expect(err).To(BeNil())
+	})
+
+	It("should read configuration from environment", func() {
+		os.Setenv(devidentity.EnvEnabled, "true")
+		os.Setenv(devidentity.EnvOrgID, "42")
+		os.Setenv(devidentity.EnvType, "System")
+		defer func() {
+			os.Unsetenv(devidentity.EnvEnabled)
+			os.Unsetenv(devidentity.EnvOrgID)
+			os.Unsetenv(devidentity.EnvType)
+		}()
+
+		Expect(devidentity.ConfigFromEnv()).To(Equal(devidentity.Config{Enabled: true, OrgID: "42", Type: identity.TypeSystem}))
+	})
+})
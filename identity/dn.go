@@ -0,0 +1,350 @@
+package identity
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidDN       = errors.New("invalid distinguished name")
+	ErrX509NotAllowed  = errors.New("x-rh-identity certificate is not allowed")
+	ErrX509MissingCert = errors.New("x-rh-identity header is missing x509 details")
+)
+
+// attribute type names and their OIDs, names are used by NormalizeDN
+var dnAttributes = []struct {
+	name string
+	oid  asn1.ObjectIdentifier
+}{
+	{"CN", asn1.ObjectIdentifier{2, 5, 4, 3}},
+	{"SERIALNUMBER", asn1.ObjectIdentifier{2, 5, 4, 5}},
+	{"C", asn1.ObjectIdentifier{2, 5, 4, 6}},
+	{"L", asn1.ObjectIdentifier{2, 5, 4, 7}},
+	{"ST", asn1.ObjectIdentifier{2, 5, 4, 8}},
+	{"STREET", asn1.ObjectIdentifier{2, 5, 4, 9}},
+	{"O", asn1.ObjectIdentifier{2, 5, 4, 10}},
+	{"OU", asn1.ObjectIdentifier{2, 5, 4, 11}},
+	{"POSTALCODE", asn1.ObjectIdentifier{2, 5, 4, 17}},
+	{"UID", asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 1}},
+	{"DC", asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 25}},
+	{"EMAILADDRESS", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}},
+}
+
+func attributeOID(name string) (asn1.ObjectIdentifier, error) {
+	upper := strings.ToUpper(name)
+	for _, a := range dnAttributes {
+		if a.name == upper {
+			return a.oid, nil
+		}
+	}
+
+	// dotted decimal form, e.g. 2.5.4.3
+	var oid asn1.ObjectIdentifier
+	for _, part := range strings.Split(name, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: unknown attribute type %q", ErrInvalidDN, name)
+		}
+		oid = append(oid, n)
+	}
+	if len(oid) < 2 {
+		return nil, fmt.Errorf("%w: unknown attribute type %q", ErrInvalidDN, name)
+	}
+	return oid, nil
+}
+
+func attributeName(oid asn1.ObjectIdentifier) string {
+	for _, a := range dnAttributes {
+		if a.oid.Equal(oid) {
+			return a.name
+		}
+	}
+	return oid.String()
+}
+
+// ParseDN parses a distinguished name into a RDN sequence which can be used with
+// crypto/x509/pkix, for example via pkix.Name.FillFromRDNSequence. Both RFC 4514
+// ("CN=svc,OU=Insights,O=Red Hat") and OpenSSL ("/O=Red Hat/OU=Insights/CN=svc")
+// formats are supported, the OpenSSL format is recognized by the leading slash.
+// The sequence is in ASN.1 order, i.e. reversed to the RFC 4514 string.
+func ParseDN(dn string) (pkix.RDNSequence, error) {
+	dn = strings.TrimSpace(dn)
+	if dn == "" {
+		return pkix.RDNSequence{}, nil
+	}
+
+	var rdns []string
+	openSSL := strings.HasPrefix(dn, "/")
+	if openSSL {
+		rdns = splitEscaped(dn[1:], "/")
+	} else {
+		rdns = splitEscaped(dn, ",;")
+	}
+
+	seq := make(pkix.RDNSequence, 0, len(rdns))
+	for _, rdn := range rdns {
+		var set pkix.RelativeDistinguishedNameSET
+		for _, atv := range splitEscaped(rdn, "+") {
+			name, value, ok := strings.Cut(atv, "=")
+			if !ok {
+				return nil, fmt.Errorf("%w: missing '=' in %q", ErrInvalidDN, atv)
+			}
+			oid, err := attributeOID(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			v, err := unescapeDNValue(value)
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, pkix.AttributeTypeAndValue{Type: oid, Value: v})
+		}
+		seq = append(seq, set)
+	}
+
+	if !openSSL {
+		for i, j := 0, len(seq)-1; i < j; i, j = i+1, j-1 {
+			seq[i], seq[j] = seq[j], seq[i]
+		}
+	}
+	return seq, nil
+}
+
+// ParseDNName parses a distinguished name (see ParseDN) into pkix.Name.
+func ParseDNName(dn string) (pkix.Name, error) {
+	var name pkix.Name
+	seq, err := ParseDN(dn)
+	if err != nil {
+		return name, err
+	}
+	name.FillFromRDNSequence(&seq)
+	return name, nil
+}
+
+// NormalizeDN returns the distinguished name in RFC 4514 format with upper case
+// attribute types, lower case values and no insignificant whitespace, suitable for
+// comparisons of names in different formats.
+func NormalizeDN(dn string) (string, error) {
+	seq, err := ParseDN(dn)
+	if err != nil {
+		return "", err
+	}
+	return formatDN(seq, true), nil
+}
+
+// formatDN returns the RDN sequence in RFC 4514 format
+func formatDN(seq pkix.RDNSequence, lower bool) string {
+	var b strings.Builder
+	for i := len(seq) - 1; i >= 0; i-- {
+		if i != len(seq)-1 {
+			b.WriteByte(',')
+		}
+		for j, atv := range seq[i] {
+			if j > 0 {
+				b.WriteByte('+')
+			}
+			value := fmt.Sprint(atv.Value)
+			if lower {
+				value = strings.ToLower(value)
+			}
+			b.WriteString(attributeName(atv.Type))
+			b.WriteByte('=')
+			b.WriteString(escapeDNValue(value))
+		}
+	}
+	return b.String()
+}
+
+// splitEscaped splits s at any of the separators which are not escaped by a backslash
+// or enclosed in double quotes
+func splitEscaped(s, separators string) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.IndexByte(separators, s[i]) >= 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescapeDNValue(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+1 >= len(value) {
+			return "", fmt.Errorf("%w: trailing backslash in %q", ErrInvalidDN, value)
+		}
+		if i+2 < len(value) {
+			if decoded, err := hex.DecodeString(value[i+1 : i+3]); err == nil {
+				b.Write(decoded)
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(value[i+1])
+		i++
+	}
+	return b.String(), nil
+}
+
+func escapeDNValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		escape := strings.IndexByte(",+\"\\<>;=", c) >= 0 ||
+			(i == 0 && (c == ' ' || c == '#')) ||
+			(i == len(value)-1 && c == ' ')
+		if escape {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// MatchDN returns true when the distinguished name matches the pattern. Both are
+// parsed with ParseDN and must have the same attribute types in the same order,
+// values are compared case-insensitively and the "*" wildcard in pattern values
+// matches any sequence of characters. The pattern "*" matches any name.
+func MatchDN(pattern, dn string) (bool, error) {
+	if strings.TrimSpace(pattern) == "*" {
+		return true, nil
+	}
+
+	ps, err := ParseDN(pattern)
+	if err != nil {
+		return false, err
+	}
+	ds, err := ParseDN(dn)
+	if err != nil {
+		return false, err
+	}
+
+	if len(ps) != len(ds) {
+		return false, nil
+	}
+	for i := range ps {
+		if len(ps[i]) != len(ds[i]) {
+			return false, nil
+		}
+		for j := range ps[i] {
+			p, d := ps[i][j], ds[i][j]
+			if !p.Type.Equal(d.Type) {
+				return false, nil
+			}
+			if !matchWildcard(strings.ToLower(fmt.Sprint(p.Value)), strings.ToLower(fmt.Sprint(d.Value))) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// matchWildcard matches s against pattern where "*" matches any sequence of characters
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+// X509Options configure the X509 certificate allowlist.
+type X509Options struct {
+	// Subjects are allowed subject DN patterns, see MatchDN. No subject is allowed
+	// when empty, the "*" pattern allows any subject.
+	Subjects []string
+
+	// Issuers are allowed issuer DN patterns, see MatchDN. No issuer is allowed when
+	// empty, the "*" pattern allows any issuer.
+	Issuers []string
+}
+
+// RequireX509 returns a policy that accepts only X509 identities with subject and
+// issuer matching the allowlist. The function panics when subjects or issuers are
+// empty or a pattern is not a valid distinguished name.
+func RequireX509(opts X509Options) Policy {
+	opts.mustCompile()
+	return namedPolicy{
+		name: fmt.Sprintf("RequireX509(%s)", opts),
+		fn: func(id *XRHID) error {
+			return checkX509(id, opts)
+		},
+	}
+}
+
+func (opts X509Options) String() string {
+	return fmt.Sprintf("subjects=%s; issuers=%s", strings.Join(opts.Subjects, " | "), strings.Join(opts.Issuers, " | "))
+}
+
+// mustCompile panics when subjects or issuers are empty or a pattern is not a valid
+// distinguished name
+func (opts X509Options) mustCompile() {
+	if len(opts.Subjects) == 0 || len(opts.Issuers) == 0 {
+		panic(`identity: X509Options must list subjects and issuers, use "*" to allow any`)
+	}
+	for _, p := range append(append([]string(nil), opts.Subjects...), opts.Issuers...) {
+		if _, err := MatchDN(p, ""); err != nil {
+			panic(fmt.Sprintf("identity: malformed DN pattern %q: %v", p, err))
+		}
+	}
+}
+
+// checkX509 returns nil when the identity is X509 with subject and issuer matching
+// the allowlist
+func checkX509(id *XRHID, opts X509Options) error {
+	if !id.Identity.IsX509() {
+		return fmt.Errorf("%w: %q", ErrPolicyIdentityType, id.Identity.Type)
+	}
+	cert := id.Identity.X509
+	if cert == nil {
+		return ErrX509MissingCert
+	}
+	if !matchAnyDN(opts.Subjects, cert.SubjectDN) {
+		return fmt.Errorf("%w: subject %q", ErrX509NotAllowed, cert.SubjectDN)
+	}
+	if !matchAnyDN(opts.Issuers, cert.IssuerDN) {
+		return fmt.Errorf("%w: issuer %q", ErrX509NotAllowed, cert.IssuerDN)
+	}
+	return nil
+}
+
+func matchAnyDN(patterns []string, dn string) bool {
+	for _, p := range patterns {
+		if ok, err := MatchDN(p, dn); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package identity_test
+
+import (
+	"crypto/x509/pkix"
+	"errors"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Distinguished names", func() {
+	It("should parse both formats into the same sequence", func() {
+		openSSL, err := identity.ParseDN("/O=Red Hat/OU=Insights/CN=service.example.com")
+		Expect(err).To(BeNil())
+		rfc, err := identity.ParseDN("CN=service.example.com, OU=Insights, O=Red Hat")
+		Expect(err).To(BeNil())
+		Expect(rfc).To(Equal(openSSL))
+		Expect(rfc.String()).To(Equal("CN=service.example.com,OU=Insights,O=Red Hat"))
+	})
+
+	It("should be compatible with pkix.Name", func() {
+		name, err := identity.ParseDNName("CN=svc+UID=42,OU=Insights,OU=prod,O=Red Hat,C=US")
+		Expect(err).To(BeNil())
+		Expect(name.CommonName).To(Equal("svc"))
+		Expect(name.OrganizationalUnit).To(Equal([]string{"prod", "Insights"}))
+		Expect(name.Organization).To(Equal([]string{"Red Hat"}))
+		Expect(name.Country).To(Equal([]string{"US"}))
+
+		var seq pkix.RDNSequence
+		seq, err = identity.ParseDN("CN=svc+UID=42")
+		Expect(err).To(BeNil())
+		Expect(seq[0]).To(HaveLen(2))
+	})
+
+	It("should handle escapes", func() {
+		name, err := identity.ParseDNName(`CN=Doe\, John,O=\52ed Hat`)
+		Expect(err).To(BeNil())
+		Expect(name.CommonName).To(Equal("Doe, John"))
+		Expect(name.Organization).To(Equal([]string{"Red Hat"}))
+
+		name, err = identity.ParseDNName(`/O=Red Hat/CN=a\/b`)
+		Expect(err).To(BeNil())
+		Expect(name.CommonName).To(Equal("a/b"))
+	})
+
+	It("should normalize names", func() {
+		a, err := identity.NormalizeDN("/o=Red Hat/OU=Insights/cn=Service.Example.com")
+		Expect(err).To(BeNil())
+		b, err := identity.NormalizeDN("CN = service.example.com ,OU=insights,O=RED HAT")
+		Expect(err).To(BeNil())
+		Expect(a).To(Equal("CN=service.example.com,OU=insights,O=red hat"))
+		Expect(b).To(Equal(a))
+	})
+
+	It("should reject malformed names", func() {
+		_, err := identity.ParseDN("CN=a,Red Hat")
+		Expect(errors.Is(err, identity.ErrInvalidDN)).To(BeTrue())
+		_, err = identity.ParseDN("XX=a")
+		Expect(errors.Is(err, identity.ErrInvalidDN)).To(BeTrue())
+	})
+
+	It("should match wildcards", func() {
+		dn := "/O=Red Hat/OU=Insights/CN=service.example.com"
+		for _, pattern := range []string{"*", "CN=*.example.com,OU=Insights,O=Red Hat", "/O=red hat/OU=*/CN=service.*"} {
+			ok, err := identity.MatchDN(pattern, dn)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue(), pattern)
+		}
+		for _, pattern := range []string{"CN=*.example.org,OU=Insights,O=Red Hat", "CN=*,O=Red Hat", "CN=*,OU=*,O=*,C=US"} {
+			ok, err := identity.MatchDN(pattern, dn)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeFalse(), pattern)
+		}
+	})
+})
+
+var _ = Describe("RequireX509", func() {
+	policy := identity.RequireX509(identity.X509Options{
+		Subjects: []string{"CN=*.example.com,OU=Insights,O=Red Hat"},
+		Issuers:  []string{"CN=Certificate Authority,OU=prod,O=Red Hat"},
+	})
+
+	check := func(b *identitytest.Builder) error {
+		id := b.Build()
+		return policy.Check(&id)
+	}
+
+	It("should accept allowed certificates", func() {
+		Expect(check(identitytest.X509())).To(Succeed())
+	})
+
+	It("should reject other subjects and issuers", func() {
+		err := check(identitytest.X509().SubjectDN("/O=Red Hat/OU=Insights/CN=evil.example.org"))
+		Expect(errors.Is(err, identity.ErrX509NotAllowed)).To(BeTrue())
+		Expect(identity.ErrorCode(err)).To(Equal("x509_not_allowed"))
+
+		err = check(identitytest.X509().IssuerDN("/O=Red Hat/OU=stage/CN=Certificate Authority"))
+		Expect(errors.Is(err, identity.ErrX509NotAllowed)).To(BeTrue())
+	})
+
+	It("should reject other identity types", func() {
+		err := check(identitytest.User())
+		Expect(errors.Is(err, identity.ErrPolicyIdentityType)).To(BeTrue())
+	})
+
+	It("should panic on malformed patterns", func() {
+		Expect(func() {
+			identity.RequireX509(identity.X509Options{Subjects: []string{"bogus"}, Issuers: []string{"*"}})
+		}).To(Panic())
+	})
+
+	It("should panic on empty allowlists", func() {
+		Expect(func() { identity.RequireX509(identity.X509Options{}) }).To(Panic())
+		Expect(func() { identity.RequireX509(identity.X509Options{Subjects: []string{"*"}}) }).To(Panic())
+	})
+
+	It("should accept any certificate only when explicitly allowed", func() {
+		allowAll := identity.RequireX509(identity.X509Options{Subjects: []string{"*"}, Issuers: []string{"*"}})
+		id := identitytest.X509().SubjectDN("/CN=attacker").IssuerDN("/CN=evil CA").Build()
+		Expect(allowAll.Check(&id)).To(Succeed())
+	})
+})
@@ -0,0 +1,92 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var ErrNotEntitled = errors.New("x-rh-identity is not entitled")
+
+// EntitlementOptions configure entitlement checks.
+type EntitlementOptions struct {
+	// Bundles is the list of entitlement keys (e.g. "insights", "cost_management").
+	Bundles []string
+
+	// RequireAll requires the identity to be entitled to all bundles, by default
+	// entitlement to any of the bundles is sufficient.
+	RequireAll bool
+
+	// AllowTrial accepts trial entitlements, by default trials are rejected.
+	AllowTrial bool
+}
+
+// IsEntitled returns true when the identity is entitled to the given bundle. Trial
+// entitlements are only accepted when allowTrial is true.
+func (x *XRHID) IsEntitled(bundle string, allowTrial bool) bool {
+	sd, ok := x.Entitlements[bundle]
+	if !ok || !sd.IsEntitled {
+		return false
+	}
+	return allowTrial || !sd.IsTrial
+}
+
+// CheckEntitlements returns nil when the identity is entitled to the configured bundles
+// or an error wrapping ErrNotEntitled which lists the offending bundles. Identities are
+// rejected when no bundles are configured.
+func CheckEntitlements(id *XRHID, opts EntitlementOptions) error {
+	if len(opts.Bundles) == 0 {
+		return fmt.Errorf("%w: no bundles configured", ErrNotEntitled)
+	}
+
+	var missing []string
+	for _, bundle := range opts.Bundles {
+		if id.IsEntitled(bundle, opts.AllowTrial) {
+			if !opts.RequireAll {
+				return nil
+			}
+			continue
+		}
+
+		if id.IsEntitled(bundle, true) {
+			missing = append(missing, bundle+" (trial)")
+		} else {
+			missing = append(missing, bundle)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if opts.RequireAll {
+		return fmt.Errorf("%w to all of: %s", ErrNotEntitled, strings.Join(missing, ", "))
+	}
+	return fmt.Errorf("%w to any of: %s", ErrNotEntitled, strings.Join(missing, ", "))
+}
+
+// RequireEntitlements returns a policy performing CheckEntitlements. The function
+// panics when no bundles are configured.
+func RequireEntitlements(opts EntitlementOptions) Policy {
+	if len(opts.Bundles) == 0 {
+		panic("identity: RequireEntitlements needs at least one bundle")
+	}
+	mode := "any"
+	if opts.RequireAll {
+		mode = "all"
+	}
+	return namedPolicy{
+		name: fmt.Sprintf("RequireEntitlements(%s of %s, trial=%t)", mode, strings.Join(opts.Bundles, ", "), opts.AllowTrial),
+		fn: func(id *XRHID) error {
+			return CheckEntitlements(id, opts)
+		},
+	}
+}
+
+// EnforceEntitlements checks entitlements of the identity stored in the request
+// context. The middleware must be placed after EnforceIdentityWithLogger or one of its
+// variants. Requests without required entitlements are aborted with HTTP code 403.
+func EnforceEntitlements(logger ErrorFunc, opts EntitlementOptions) func(next http.Handler) http.Handler {
+	return enforcePolicies(logger, RequireEntitlements(opts))
+}
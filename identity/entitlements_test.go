@@ -0,0 +1,83 @@
+package identity_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Entitlements", func() {
+	var id identity.XRHID
+
+	BeforeEach(func() {
+		id = identity.XRHID{
+			Entitlements: map[string]identity.ServiceDetails{
+				"insights":        {IsEntitled: true},
+				"cost_management": {IsEntitled: true, IsTrial: true},
+				"ansible":         {IsEntitled: false},
+			},
+		}
+	})
+
+	Context("With CheckEntitlements", func() {
+		It("should accept any of the bundles", func() {
+			err := identity.CheckEntitlements(&id, identity.EntitlementOptions{Bundles: []string{"ansible", "insights"}})
+			Expect(err).To(BeNil())
+		})
+
+		It("should reject when no bundles are configured", func() {
+			err := identity.CheckEntitlements(&id, identity.EntitlementOptions{})
+			Expect(errors.Is(err, identity.ErrNotEntitled)).To(BeTrue())
+			Expect(func() { identity.EnforceEntitlements(nil, identity.EntitlementOptions{}) }).To(Panic())
+		})
+
+		It("should reject when all bundles are required", func() {
+			err := identity.CheckEntitlements(&id, identity.EntitlementOptions{Bundles: []string{"ansible", "insights"}, RequireAll: true})
+			Expect(errors.Is(err, identity.ErrNotEntitled)).To(BeTrue())
+			Expect(err.Error()).To(Equal("x-rh-identity is not entitled to all of: ansible"))
+		})
+
+		It("should reject trials by default", func() {
+			err := identity.CheckEntitlements(&id, identity.EntitlementOptions{Bundles: []string{"cost_management", "openshift"}})
+			Expect(err.Error()).To(Equal("x-rh-identity is not entitled to any of: cost_management (trial), openshift"))
+		})
+
+		It("should accept trials when allowed", func() {
+			err := identity.CheckEntitlements(&id, identity.EntitlementOptions{Bundles: []string{"cost_management", "insights"}, RequireAll: true, AllowTrial: true})
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Context("With EnforceEntitlements middleware", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			req, _ = http.NewRequest("GET", "/api/entitlements/v1/services/", nil)
+			req.Header.Set("x-rh-identity", getBase64(exampleHeader))
+		})
+
+		serve := func(opts identity.EntitlementOptions, allowPass bool) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			handler := identity.EnforceIdentityWithLogger(noopLogger)(
+				identity.EnforceEntitlements(noopLogger, opts)(GetTestHandler(allowPass)))
+			handler.ServeHTTP(rr, req)
+			return rr
+		}
+
+		It("should pass entitled identities", func() {
+			rr := serve(identity.EntitlementOptions{Bundles: []string{"insights"}}, true)
+			Expect(rr.Code).To(Equal(200))
+		})
+
+		It("should 403 with a clear reason", func() {
+			rr := serve(identity.EntitlementOptions{Bundles: []string{"insights", "openshift"}, RequireAll: true}, false)
+			Expect(rr.Code).To(Equal(403))
+			Expect(rr.Body.String()).To(Equal("Forbidden: x-rh-identity is not entitled to all of: openshift\n"))
+		})
+	})
+})
@@ -0,0 +1,135 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Exemption decides whether identity decoding should be skipped for a request.
+// Built-in exemptions also implement fmt.Stringer which returns a short
+// human-readable description.
+type Exemption interface {
+	Exempt(r *http.Request) bool
+}
+
+// ExemptionFunc is an adapter to allow the use of ordinary functions as exemptions.
+type ExemptionFunc func(r *http.Request) bool
+
+// Exempt calls f(r).
+func (f ExemptionFunc) Exempt(r *http.Request) bool {
+	return f(r)
+}
+
+// namedExemption is an exemption with a description, all built-in exemptions use it.
+type namedExemption struct {
+	name string
+	fn   func(r *http.Request) bool
+}
+
+func (e namedExemption) Exempt(r *http.Request) bool {
+	return e.fn(r)
+}
+
+func (e namedExemption) String() string {
+	return e.name
+}
+
+// ExemptPaths returns an exemption matching request paths exactly.
+func ExemptPaths(paths ...string) Exemption {
+	return namedExemption{
+		name: fmt.Sprintf("ExemptPaths(%s)", strings.Join(paths, ", ")),
+		fn: func(r *http.Request) bool {
+			for _, p := range paths {
+				if r.URL.Path == p {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// ExemptPrefixes returns an exemption matching request paths by prefix.
+func ExemptPrefixes(prefixes ...string) Exemption {
+	return namedExemption{
+		name: fmt.Sprintf("ExemptPrefixes(%s)", strings.Join(prefixes, ", ")),
+		fn: func(r *http.Request) bool {
+			for _, p := range prefixes {
+				if strings.HasPrefix(r.URL.Path, p) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// ExemptGlobs returns an exemption matching request paths against glob patterns with
+// path.Match syntax, for example "/api/*/v1/openapi.json". Note the star wildcard
+// does not match the slash character. The function panics on malformed patterns.
+func ExemptGlobs(patterns ...string) Exemption {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			panic(fmt.Sprintf("identity: malformed exemption pattern %q: %v", p, err))
+		}
+	}
+
+	return namedExemption{
+		name: fmt.Sprintf("ExemptGlobs(%s)", strings.Join(patterns, ", ")),
+		fn: func(r *http.Request) bool {
+			for _, p := range patterns {
+				if ok, _ := path.Match(p, r.URL.Path); ok {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// ExemptMethods returns an exemption matching HTTP methods, for example http.MethodOptions.
+func ExemptMethods(methods ...string) Exemption {
+	return namedExemption{
+		name: fmt.Sprintf("ExemptMethods(%s)", strings.Join(methods, ", ")),
+		fn: func(r *http.Request) bool {
+			for _, m := range methods {
+				if strings.EqualFold(r.Method, m) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// WithExemptions appends exemptions, when any of them matches a request the identity
+// is not decoded nor checked and the request is passed to the next handler. The
+// matching exemption can be retrieved from the context via GetExemption.
+func WithExemptions(exemptions ...Exemption) Option {
+	return func(m *middleware) {
+		m.exemptions = append(m.exemptions, exemptions...)
+	}
+}
+
+// GetExemption returns the description of the exemption which matched the request
+// and true, or empty string and false when identity was not skipped.
+func GetExemption(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(exemptionKey).(string)
+	return name, ok
+}
+
+// matchExemption returns the description of the first matching exemption
+func (m *middleware) matchExemption(r *http.Request) (string, bool) {
+	for _, e := range m.exemptions {
+		if e.Exempt(r) {
+			if s, ok := e.(fmt.Stringer); ok {
+				return s.String(), true
+			}
+			return "custom exemption", true
+		}
+	}
+	return "", false
+}
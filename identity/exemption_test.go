@@ -0,0 +1,61 @@
+package identity_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exemptions", func() {
+	var exemption string
+
+	serve := func(method, target string) int {
+		exemption = ""
+		handler := identity.EnforceIdentityWithOptions(identity.WithExemptions(
+			identity.ExemptPaths("/healthz", "/metrics"),
+			identity.ExemptPrefixes("/public/"),
+			identity.ExemptGlobs("/api/*/v1/openapi.json"),
+			identity.ExemptMethods(http.MethodOptions),
+		))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			exemption, _ = identity.GetExemption(r.Context())
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr.Code
+	}
+
+	It("should skip exact paths", func() {
+		Expect(serve("GET", "/metrics")).To(Equal(200))
+		Expect(exemption).To(Equal("ExemptPaths(/healthz, /metrics)"))
+	})
+
+	It("should skip prefixes", func() {
+		Expect(serve("GET", "/public/index.html")).To(Equal(200))
+		Expect(exemption).To(Equal("ExemptPrefixes(/public/)"))
+	})
+
+	It("should skip glob patterns", func() {
+		Expect(serve("GET", "/api/inventory/v1/openapi.json")).To(Equal(200))
+		Expect(exemption).To(Equal("ExemptGlobs(/api/*/v1/openapi.json)"))
+	})
+
+	It("should skip methods", func() {
+		Expect(serve("OPTIONS", "/api/inventory/v1/hosts")).To(Equal(200))
+		Expect(exemption).To(Equal("ExemptMethods(OPTIONS)"))
+	})
+
+	It("should enforce identity on other requests", func() {
+		Expect(serve("GET", "/api/inventory/v1/hosts")).To(Equal(400))
+		Expect(serve("GET", "/healthz/deep")).To(Equal(400))
+		Expect(serve("GET", "/api/inventory/v2/openapi.json")).To(Equal(400))
+	})
+
+	It("should panic on malformed patterns", func() {
+		Expect(func() { identity.ExemptGlobs("/api/[") }).To(Panic())
+	})
+})
@@ -0,0 +1,391 @@
+package identity
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fields which are not modelled by the identity types are kept in XRHID.Extra keyed by
+// their JSON Pointer (RFC 6901), so decoding and encoding an identity does not lose any
+// information. Extra fields are collected when the whole XRHID is unmarshalled and
+// written back into their parent objects by XRHID.MarshalJSON. Nested types do not hold
+// extra fields, so they stay comparable, and unmarshalling them on their own drops
+// unknown fields. Extra fields are ignored by all checks and policies.
+
+// knownFields caches JSON fields of struct types
+var knownFields sync.Map
+
+type jsonField struct {
+	name string
+	key  []byte // name as bytes
+	typ  reflect.Type
+}
+
+// jsonFields returns JSON fields of all exported fields of the struct type keyed by
+// lowercase name, encoding/json matches keys case-insensitively
+func jsonFields(t reflect.Type) map[string]jsonField {
+	if fields, ok := knownFields.Load(t); ok {
+		return fields.(map[string]jsonField)
+	}
+
+	fields := make(map[string]jsonField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = jsonField{name: name, key: []byte(name), typ: f.Type}
+	}
+
+	knownFields.Store(t, fields)
+	return fields
+}
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// xrhidType is the type whose fields are known when collecting extra fields
+var xrhidType = reflect.TypeOf(XRHID{})
+
+// collectExtra returns fields of the JSON document which are unknown to the XRHID
+// types keyed by JSON Pointer, nil when there are none. Like encoding/json, keys are
+// matched case-insensitively and the last duplicate wins. The document must be valid
+// JSON.
+func collectExtra(data []byte) map[string]json.RawMessage {
+	var path [8][]byte
+	c := extraCollector{path: path[:0]}
+	c.collect(data, skipSpace(data, 0), xrhidType)
+	return c.extra
+}
+
+// extraCollector walks the document along the types in a single pass. The path of the
+// current object is only turned into a JSON Pointer when an unknown field is found, so
+// documents without unknown fields are scanned without allocations.
+type extraCollector struct {
+	path  [][]byte
+	extra map[string]json.RawMessage
+}
+
+// collect collects extra fields of the value of type t starting at i and returns the
+// index after the value
+func (c *extraCollector) collect(data []byte, i int, t reflect.Type) int {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	kind := t.Kind()
+	if (kind != reflect.Map && kind != reflect.Struct) || i >= len(data) || data[i] != '{' {
+		return skipValue(data, i)
+	}
+
+	var fields map[string]jsonField
+	if kind == reflect.Struct {
+		fields = jsonFields(t)
+	}
+
+	for i++; ; i++ {
+		i = skipSpace(data, i)
+		if i >= len(data) || data[i] != '"' {
+			// end of an empty object
+			return i + 1
+		}
+		end := skipString(data, i)
+		key := data[i+1 : end-1]
+		if bytes.IndexByte(key, '\\') >= 0 {
+			var unquoted string
+			_ = json.Unmarshal(data[i:end], &unquoted)
+			key = []byte(unquoted)
+		}
+		// skip the colon
+		i = skipSpace(data, skipSpace(data, end)+1)
+
+		var token []byte
+		var vt reflect.Type
+		if kind == reflect.Map {
+			token, vt = key, t.Elem()
+		} else if f, ok := lookupField(fields, key); ok {
+			token, vt = f.key, f.typ
+		}
+		if vt != nil {
+			c.path = append(c.path, token)
+			end = c.collect(data, i, vt)
+			c.path = c.path[:len(c.path)-1]
+		} else {
+			end = skipValue(data, i)
+			c.add(key, data[i:end])
+		}
+
+		i = skipSpace(data, end)
+		if i >= len(data) || data[i] != ',' {
+			return i + 1
+		}
+	}
+}
+
+func (c *extraCollector) add(key []byte, raw []byte) {
+	var b strings.Builder
+	for _, token := range append(c.path, key) {
+		b.WriteByte('/')
+		b.WriteString(pointerEscaper.Replace(string(token)))
+	}
+	if c.extra == nil {
+		c.extra = make(map[string]json.RawMessage)
+	}
+	c.extra[b.String()] = append(json.RawMessage(nil), raw...)
+}
+
+// lookupField returns the struct field of the JSON key, matched case-insensitively
+func lookupField(fields map[string]jsonField, key []byte) (jsonField, bool) {
+	if f, ok := fields[string(key)]; ok {
+		return f, true
+	}
+	for _, f := range fields {
+		if bytes.EqualFold(f.key, key) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+// fieldIter iterates over members of a valid JSON object. Splitting a document this
+// way is several times faster than unmarshalling into map[string]json.RawMessage.
+type fieldIter struct {
+	data []byte
+	i    int // start of the next member, -1 when done
+	key  []byte
+	raw  []byte
+}
+
+func newFieldIter(data []byte) fieldIter {
+	i := skipSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return fieldIter{i: -1}
+	}
+	return fieldIter{data: data, i: i + 1}
+}
+
+// next advances to the next member and returns false when there are no more members
+func (it *fieldIter) next() bool {
+	if it.i < 0 {
+		return false
+	}
+	data := it.data
+
+	i := skipSpace(data, it.i)
+	if i >= len(data) || data[i] != '"' {
+		it.i = -1
+		return false
+	}
+	end := skipValue(data, i)
+	it.key = data[i+1 : end-1]
+	if bytes.IndexByte(it.key, '\\') >= 0 {
+		var unquoted string
+		_ = json.Unmarshal(data[i:end], &unquoted)
+		it.key = []byte(unquoted)
+	}
+
+	i = skipSpace(data, end)
+	if i >= len(data) || data[i] != ':' {
+		it.i = -1
+		return false
+	}
+	i = skipSpace(data, i+1)
+	end = skipValue(data, i)
+	it.raw = data[i:end]
+
+	it.i = -1
+	if i = skipSpace(data, end); i < len(data) && data[i] == ',' {
+		it.i = i + 1
+	}
+	return true
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// delimiters end JSON literals and numbers
+var delimiters = [256]bool{',': true, ':': true, '}': true, ']': true, ' ': true, '\t': true, '\n': true, '\r': true}
+
+// skipValue returns the index after the valid JSON value starting at i
+func skipValue(data []byte, i int) int {
+	depth := 0
+	for i < len(data) {
+		switch data[i] {
+		case '"':
+			i = skipString(data, i)
+		case '{', '[':
+			depth++
+			i++
+		case '}', ']':
+			depth--
+			i++
+		case ',', ':', ' ', '\t', '\n', '\r':
+			if depth == 0 {
+				return i
+			}
+			i++
+		default:
+			// literal or number
+			for i < len(data) && !delimiters[data[i]] {
+				i++
+			}
+		}
+		if depth == 0 {
+			return i
+		}
+	}
+	return i
+}
+
+// skipString returns the index after the valid JSON string starting at i
+func skipString(data []byte, i int) int {
+	i++
+	if n := bytes.IndexByte(data[i:], '"'); n >= 0 && bytes.IndexByte(data[i:i+n], '\\') < 0 {
+		return i + n + 1
+	}
+
+	// the string contains escape sequences, the quote may be escaped
+	for ; i < len(data) && data[i] != '"'; i++ {
+		if data[i] == '\\' {
+			i++
+		}
+	}
+	return i + 1
+}
+
+// injectExtra returns the JSON object with extra fields under prefix inserted into
+// the object itself and into nested objects. Extra fields colliding with existing keys
+// are skipped, fields of objects which are not present are dropped.
+func injectExtra(data []byte, prefix string, extra map[string]json.RawMessage) ([]byte, error) {
+	if i := skipSpace(data, 0); i >= len(data) || data[i] != '{' {
+		return data, nil
+	}
+
+	var direct []string
+	nested := make(map[string]bool)
+	for path := range extra {
+		rest, ok := strings.CutPrefix(path, prefix+"/")
+		if !ok {
+			continue
+		}
+		if token, _, deeper := strings.Cut(rest, "/"); deeper {
+			nested[token] = true
+		} else {
+			direct = append(direct, path)
+		}
+	}
+	if len(direct) == 0 && len(nested) == 0 {
+		return data, nil
+	}
+	sort.Strings(direct)
+
+	var b bytes.Buffer
+	var err error
+	seen := make(map[string]bool)
+	writeField := func(name string, value []byte) {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		b.Write(key)
+		b.WriteByte(':')
+		if cerr := json.Compact(&b, value); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	b.WriteByte('{')
+	for it := newFieldIter(data); it.next(); {
+		raw := it.raw
+		token := pointerEscaper.Replace(string(it.key))
+		seen[strings.ToLower(string(it.key))] = true
+		if nested[token] {
+			injected, ierr := injectExtra(raw, prefix+"/"+token, extra)
+			if ierr != nil && err == nil {
+				err = ierr
+			}
+			raw = injected
+		}
+		writeField(string(it.key), raw)
+	}
+	for _, path := range direct {
+		name := pointerUnescaper.Replace(path[len(prefix)+1:])
+		if !seen[strings.ToLower(name)] {
+			writeField(name, extra[path])
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes(), err
+}
+
+// cloneExtra returns a copy of the extra fields map
+func cloneExtra(extra map[string]json.RawMessage) map[string]json.RawMessage {
+	if extra == nil {
+		return nil
+	}
+	c := make(map[string]json.RawMessage, len(extra))
+	for k, v := range extra {
+		c[k] = v
+	}
+	return c
+}
+
+// unknownFields returns dot-separated paths of all extra fields sorted
+func (x *XRHID) unknownFields() []string {
+	paths := make([]string, 0, len(x.Extra))
+	for pointer := range x.Extra {
+		tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+		for i, t := range tokens {
+			tokens[i] = pointerUnescaper.Replace(t)
+		}
+		paths = append(paths, strings.Join(tokens, "."))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// xrhidFields is XRHID without the custom JSON methods
+type xrhidFields XRHID
+
+// unmarshalIdentity decodes the identity and collects unknown fields, it avoids the
+// second validation pass of json.Unmarshal calling XRHID.UnmarshalJSON
+func unmarshalIdentity(data []byte, x *XRHID) error {
+	if err := json.Unmarshal(data, (*xrhidFields)(x)); err != nil {
+		return err
+	}
+	x.Extra = collectExtra(data)
+	return nil
+}
+
+// UnmarshalJSON decodes the identity and collects unknown fields of the identity and
+// all nested values into the Extra field. DecodeIdentity and its variants are faster
+// than json.Unmarshal because they skip the validation pass encoding/json performs
+// before calling UnmarshalJSON.
+func (x *XRHID) UnmarshalJSON(data []byte) error {
+	return unmarshalIdentity(data, x)
+}
+
+// MarshalJSON encodes the identity including fields stored in the Extra field.
+func (x XRHID) MarshalJSON() ([]byte, error) {
+	buf, err := json.Marshal(xrhidFields(x))
+	if err != nil || len(x.Extra) == 0 {
+		return buf, err
+	}
+	return injectExtra(buf, "", x.Extra)
+}
@@ -0,0 +1,114 @@
+package identity_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// withUnknownFields adds unknown fields on every level of the identity JSON
+func withUnknownFields(header string) string {
+	raw, err := base64.StdEncoding.DecodeString(header)
+	Expect(err).To(BeNil())
+
+	var doc map[string]any
+	Expect(json.Unmarshal(raw, &doc)).To(Succeed())
+	doc["gateway"] = map[string]any{"version": 2.0}
+	id := doc["identity"].(map[string]any)
+	id["new_field"] = []any{"a", 1.0}
+	id["internal"].(map[string]any)["region"] = "us-east-1"
+	for _, key := range []string{"user", "system", "associate", "x509", "service_account"} {
+		if sub, ok := id[key].(map[string]any); ok {
+			sub["source_id"] = "2d3e8e8a-3d67-4b2e-9f9e-8c2f4b9d2c5e"
+		}
+	}
+	doc["entitlements"].(map[string]any)["insights"].(map[string]any)["attributes"] = map[string]any{"sku": "MCT0000"}
+
+	raw, err = json.Marshal(doc)
+	Expect(err).To(BeNil())
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func decodeJSONMap(header string) map[string]any {
+	raw, err := base64.StdEncoding.DecodeString(header)
+	Expect(err).To(BeNil())
+	var doc map[string]any
+	Expect(json.Unmarshal(raw, &doc)).To(Succeed())
+	return doc
+}
+
+var _ = Describe("Unknown fields", func() {
+	It("should be preserved in decode and encode round trips", func() {
+		for _, b := range identitytest.All() {
+			header := withUnknownFields(b.Header())
+
+			ctx, err := identity.DecodeIdentityCtx(context.Background(), header)
+			Expect(err).To(BeNil())
+
+			encoded := identity.EncodeIdentity(ctx)
+			Expect(decodeJSONMap(encoded)).To(Equal(decodeJSONMap(header)))
+		}
+	})
+
+	It("should be available in Extra fields", func() {
+		id, err := identity.DecodeIdentity(withUnknownFields(identitytest.System().Header()))
+		Expect(err).To(BeNil())
+		Expect(id.Extra).To(HaveKey("/gateway"))
+		Expect(string(id.Extra["/identity/internal/region"])).To(Equal(`"us-east-1"`))
+		Expect(string(id.Extra["/identity/system/source_id"])).To(Equal(`"2d3e8e8a-3d67-4b2e-9f9e-8c2f4b9d2c5e"`))
+		Expect(id.Extra).To(HaveKey("/entitlements/insights/attributes"))
+		Expect(id.Identity.User).To(BeNil())
+	})
+
+	It("should handle escapes and nested values", func() {
+		json := `{"identity": {"type": "User", "org_id": "1", "n\u0065w": {"a": "}\"]", "b": [{}, [], -1.5e3, null]}, "user": {"username": "x"}}}`
+		id, err := identity.DecodeIdentity(getBase64(json))
+		Expect(err).To(BeNil())
+		Expect(id.Extra).To(HaveLen(1))
+		Expect(string(id.Extra["/identity/new"])).To(Equal(`{"a": "}\"]", "b": [{}, [], -1.5e3, null]}`))
+		Expect(id.Identity.User.Username).To(Equal("x"))
+	})
+
+	It("should not duplicate known fields with different case", func() {
+		id, err := identity.DecodeIdentity(getBase64(`{"identity": {"type": "User", "ORG_ID": "1"}}`))
+		Expect(err).To(BeNil())
+		Expect(id.Identity.OrgID).To(Equal("1"))
+		Expect(id.Extra).To(BeNil())
+	})
+
+	It("should escape JSON Pointer tokens", func() {
+		json := `{"identity": {"type": "User", "org_id": "1", "a/b~c": 1}, "entitlements": {"x/y": {"is_entitled": true, "extra": 2}}}`
+		id, err := identity.DecodeIdentity(getBase64(json))
+		Expect(err).To(BeNil())
+		Expect(id.Extra).To(HaveKey("/identity/a~1b~0c"))
+		Expect(id.Extra).To(HaveKey("/entitlements/x~1y/extra"))
+
+		raw, err := id.MarshalJSON()
+		Expect(err).To(BeNil())
+		Expect(string(raw)).To(ContainSubstring(`"a/b~c":1`))
+		Expect(string(raw)).To(ContainSubstring(`"x/y":{"is_entitled":true,"is_trial":false,"extra":2}`))
+	})
+
+	It("should keep nested types comparable", func() {
+		a, err := identity.DecodeIdentity(withUnknownFields(identitytest.User().Header()))
+		Expect(err).To(BeNil())
+		b, err := identity.DecodeIdentity(identitytest.User().Header())
+		Expect(err).To(BeNil())
+		Expect(a.Identity.User != nil && *a.Identity.User == *b.Identity.User).To(BeTrue())
+		Expect(a.Identity.Internal == b.Identity.Internal).To(BeTrue())
+	})
+
+	It("should not be set for modelled identities", func() {
+		for _, json := range validJson {
+			id, err := identity.DecodeIdentity(getBase64(json))
+			Expect(err).To(BeNil())
+			Expect(id.Extra).To(BeNil())
+		}
+	})
+})
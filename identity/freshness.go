@@ -0,0 +1,39 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var ErrReauthenticationRequired = errors.New("x-rh-identity authentication is too old, re-authentication required")
+
+// AuthenticatedAt returns the "auth_time" field as time or zero time when not present.
+func (i *Internal) AuthenticatedAt() time.Time {
+	if i.AuthTime <= 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(i.AuthTime)
+	return time.Unix(int64(sec), int64(frac*float64(time.Second)))
+}
+
+// RequireFreshAuth returns a policy that accepts only identities which authenticated
+// within maxAge, it is intended for sensitive operations like deleting resources.
+// Identities without auth_time are rejected. The error wraps ErrReauthenticationRequired
+// which the middleware reports with HTTP code 401.
+func RequireFreshAuth(maxAge time.Duration) Policy {
+	return namedPolicy{
+		name: fmt.Sprintf("RequireFreshAuth(%s)", maxAge),
+		fn: func(id *XRHID) error {
+			authTime := id.Identity.Internal.AuthenticatedAt()
+			if authTime.IsZero() {
+				return fmt.Errorf("%w: missing auth_time", ErrReauthenticationRequired)
+			}
+			if age := time.Since(authTime); age > maxAge {
+				return fmt.Errorf("%w: authenticated %s ago", ErrReauthenticationRequired, age.Truncate(time.Second))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package identity_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"time"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Authentication freshness", func() {
+	It("should keep auth_time precision", func() {
+		id, err := identity.DecodeIdentity(getBase64(`{"identity": {"type": "User", "org_id": "1", "internal": {"auth_time": 1700000000.25}}}`))
+		Expect(err).To(BeNil())
+		Expect(id.Identity.Internal.AuthTime).To(Equal(1700000000.25))
+		Expect(id.Identity.Internal.AuthenticatedAt()).To(Equal(time.Unix(1700000000, 250000000)))
+	})
+
+	It("should return zero time when auth_time is missing", func() {
+		id := identitytest.User().Build()
+		Expect(id.Identity.Internal.AuthenticatedAt().IsZero()).To(BeTrue())
+	})
+
+	It("should accept fresh and reject old authentication", func() {
+		policy := identity.RequireFreshAuth(5 * time.Minute)
+
+		id := identitytest.User().AuthTime(time.Now().Add(-time.Minute)).Build()
+		Expect(policy.Check(&id)).To(Succeed())
+
+		id = identitytest.User().AuthTime(time.Now().Add(-time.Hour)).Build()
+		err := policy.Check(&id)
+		Expect(errors.Is(err, identity.ErrReauthenticationRequired)).To(BeTrue())
+		Expect(identity.ErrorCode(err)).To(Equal("reauthentication_required"))
+
+		id = identitytest.User().Build()
+		Expect(errors.Is(policy.Check(&id), identity.ErrReauthenticationRequired)).To(BeTrue())
+	})
+
+	It("should respond with 401", func() {
+		handler := identity.EnforceIdentityWithPolicies(noopLogger, identity.RequireFreshAuth(time.Minute))(GetTestHandler(true))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, identitytest.User().AuthTime(time.Now().Add(-time.Hour)).Request("DELETE", "/items/1"))
+		Expect(rr.Code).To(Equal(401))
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, identitytest.User().AuthTime(time.Now()).Request("DELETE", "/items/1"))
+		Expect(rr.Code).To(Equal(200))
+	})
+})
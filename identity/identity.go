@@ -29,6 +29,21 @@ logging, use EnforceIdentityWithLogger:
 	// Chi routing library example
 	r := mux.NewRouter()
 	r.Use(identity.EnforceIdentityWithLogger(ErrorLogFunc))
+
+Services with additional acceptance rules can declare an ordered list of policies
+which are checked after the base policy, the first failing policy aborts the
+request with HTTP code 403:
+
+	r.Use(identity.EnforceIdentityWithPolicies(ErrorLogFunc,
+		identity.RequireType(identity.TypeUser, identity.TypeServiceAccount),
+		identity.RequireOrgID()))
+
+For full control, use EnforceIdentityWithOptions. For example to respond with JSON
+errors in the platform format instead of plain text:
+
+	r.Use(identity.EnforceIdentityWithOptions(
+		identity.WithErrorFunc(ErrorLogFunc),
+		identity.WithErrorResponder(identity.JSONResponder)))
 */
 package identity
 
@@ -37,7 +52,6 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
 )
 
@@ -47,7 +61,7 @@ type identityKey int
 // Internal is the "internal" field of an XRHID
 type Internal struct {
 	OrgID       string  `json:"org_id"`
-	AuthTime    float32 `json:"auth_time,omitempty"`
+	AuthTime    float64 `json:"auth_time,omitempty"`
 	CrossAccess bool    `json:"cross_access,omitempty"`
 }
 
@@ -86,11 +100,16 @@ type ServiceAccount struct {
 	UserId   string `json:"user_id"`
 }
 
-// System is the "system" field of an XRHID
+// System is the "system" field of an XRHID. Only fields which are part of the
+// platform identity schema are modelled: "cn" and "cert_type" of RHSM certificates,
+// "cluster_id" of OpenShift clusters and "owner_id" of the RHSM owner of the system.
+// Any other fields the gateway adds for RHSM or hybrid-committed systems are not
+// stable and are kept in XRHID.Extra under "/identity/system/".
 type System struct {
 	CommonName string `json:"cn,omitempty"`
 	CertType   string `json:"cert_type,omitempty"`
 	ClusterId  string `json:"cluster_id,omitempty"`
+	OwnerID    string `json:"owner_id,omitempty"`
 }
 
 // Identity is the main body of the XRHID
@@ -118,11 +137,20 @@ type ServiceDetails struct {
 type XRHID struct {
 	Identity     Identity                  `json:"identity"`
 	Entitlements map[string]ServiceDetails `json:"entitlements"`
+
+	// Extra holds fields of the identity and all nested objects which are not modelled
+	// by the types, keyed by JSON Pointer (e.g. "/identity/internal/region"). Extra
+	// fields are only collected when a whole XRHID is unmarshalled.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 const (
-	parsedKey identityKey = iota
-	rawKey    identityKey = iota
+	parsedKey      identityKey = iota
+	rawKey         identityKey = iota
+	middlewareKey  identityKey = iota
+	errorKey       identityKey = iota
+	exemptionKey   identityKey = iota
+	crossAccessKey identityKey = iota
 )
 
 // Get returns the identity struct from the context or empty value when not present.
@@ -161,14 +189,7 @@ func GetIdentityHeader(ctx context.Context) string {
 //
 // Deprecated in v2, use EnforceIdentityWithLogger.
 func EnforceIdentity(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx, err := DecodeIdentityCtx(r.Context(), r.Header.Get("X-Rh-Identity"))
-		if err != nil {
-			http.Error(w, http.StatusText(400)+": "+err.Error(), 400)
-			return
-		}
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	return EnforceIdentityWithOptions()(next)
 }
 
 // GetIdentity returns the identity struct from the context or empty value when not present.
@@ -180,6 +201,22 @@ func GetIdentity(ctx context.Context) XRHID {
 	return value.(XRHID)
 }
 
+// GetIdentityOK returns the identity struct from the context and true, or empty value
+// and false when not present.
+func GetIdentityOK(ctx context.Context) (XRHID, bool) {
+	id, ok := ctx.Value(parsedKey).(XRHID)
+	return id, ok
+}
+
+// GetIdentityError returns the decoding or policy error stored in the context by
+// OptionalIdentity or nil when identity was valid or missing.
+func GetIdentityError(ctx context.Context) error {
+	if f, ok := ctx.Value(errorKey).(failure); ok {
+		return f.err
+	}
+	return nil
+}
+
 // WithIdentity returns a copy of context with identity header as a value.
 func WithIdentity(ctx context.Context, id XRHID) context.Context {
 	return context.WithValue(ctx, parsedKey, id)
@@ -229,7 +266,7 @@ var (
 // checkBasePolicy performs semantic identity check and returns nil for valid values or
 // an error instead.
 func checkBasePolicy(id *XRHID) error {
-	if (id.Identity.Type == "Associate" || id.Identity.Type == "X509") && id.Identity.AccountNumber == "" {
+	if (id.Identity.IsAssociate() || id.Identity.IsX509()) && id.Identity.AccountNumber == "" {
 		return nil
 	}
 
@@ -249,28 +286,7 @@ func checkBasePolicy(id *XRHID) error {
 //
 // To put identity into a context, use WithIdentity or DecodeIdentityCtx functions.
 func DecodeIdentity(header string) (XRHID, error) {
-	if header == "" {
-		return XRHID{}, ErrMissingIdentity
-	}
-
-	idRaw, err := base64.StdEncoding.DecodeString(header)
-	if err != nil {
-		return XRHID{}, ErrDecodeIdentity
-	}
-
-	var id XRHID
-	err = json.Unmarshal(idRaw, &id)
-	if err != nil {
-		return XRHID{}, fmt.Errorf("%w: %w", ErrUnmarshalIdentity, err)
-	}
-
-	// If org_id is not defined at the top level, use the internal one.
-	// See: https://issues.redhat.com/browse/RHCLOUD-17717
-	if id.Identity.OrgID == "" && id.Identity.Internal.OrgID != "" {
-		id.Identity.OrgID = id.Identity.Internal.OrgID
-	}
-
-	return id, nil
+	return DecodeOptions{}.DecodeIdentity(header)
 }
 
 // DecodeAndCheckIdentity returns identity value decoded from a base64 JSON encoded
@@ -280,31 +296,17 @@ func DecodeIdentity(header string) (XRHID, error) {
 // To decode identity without performing any checks, use DecodeIdentity function.
 //
 // To put identity into a context, use WithIdentity or DecodeIdentityCtx functions.
+//
+// To configure decoding, for example to enforce header limits, use DecodeOptions.
 func DecodeAndCheckIdentity(header string) (XRHID, error) {
-	id, err := DecodeIdentity(header)
-	if err != nil {
-		return XRHID{}, err
-	}
-
-	err = checkBasePolicy(&id)
-	if err != nil {
-		return XRHID{}, err
-	}
-
-	return id, nil
+	return DecodeOptions{}.DecodeAndCheckIdentity(header)
 }
 
 // DecodeIdentityCtx decodes, checks and puts identity raw string and value into
 // existing context. For more information about decode and validation process, read
 // DecodeAndCheckIdentity function documentation.
 func DecodeIdentityCtx(ctx context.Context, header string) (context.Context, error) {
-	id, err := DecodeAndCheckIdentity(header)
-	if err != nil {
-		return ctx, err
-	}
-	nc := WithIdentity(ctx, id)
-	nc = WithRawIdentity(nc, header)
-	return nc, nil
+	return DecodeOptions{}.DecodeIdentityCtx(ctx, header)
 }
 
 // EnforceIdentityWithLogger extracts, checks and places the X-Rh-Identity header into the
@@ -312,18 +314,12 @@ func DecodeIdentityCtx(ctx context.Context, header string) (context.Context, err
 // Logging callback interface can be used to implement context-aware application
 // logging.
 func EnforceIdentityWithLogger(logger ErrorFunc) func(next http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		fn := func(w http.ResponseWriter, r *http.Request) {
-			id := r.Header.Get("X-Rh-Identity")
-			ctx, err := DecodeIdentityCtx(r.Context(), id)
-			if err != nil {
-				msg := http.StatusText(400) + ": " + err.Error()
-				logger(ctx, id, msg)
-				http.Error(w, msg, 400)
-				return
-			}
-			next.ServeHTTP(w, r.WithContext(ctx))
-		}
-		return http.HandlerFunc(fn)
-	}
+	return EnforceIdentityWithOptions(WithErrorFunc(logger))
+}
+
+// EnforceIdentityWithPolicies works like EnforceIdentityWithLogger but after the identity
+// passes the base policy, all given policies are checked in order. When a policy rejects
+// the identity, the request is aborted with HTTP code 403.
+func EnforceIdentityWithPolicies(logger ErrorFunc, policies ...Policy) func(next http.Handler) http.Handler {
+	return EnforceIdentityWithOptions(WithErrorFunc(logger), WithPolicies(policies...))
 }
@@ -0,0 +1,290 @@
+/*
+Package identitytest provides builders and fixtures of Red Hat Cloud identities for
+unit tests of code which uses the identity package.
+
+Each identity type has a builder pre-populated with values which pass
+identity.DecodeAndCheckIdentity. Builders can be customized with fluent setters:
+
+	id := identitytest.User().OrgID("12345").OrgAdmin(true).Build()
+
+	req := identitytest.ServiceAccount().Request("GET", "/api/app/v1/items")
+	handler.ServeHTTP(rr, req)
+
+	ctx := identitytest.System().CertType("satellite").Context(context.Background())
+*/
+package identitytest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/internal/fixtures"
+)
+
+// Default values used by all builders.
+const (
+	DefaultOrgID         = fixtures.DefaultOrgID
+	DefaultAccountNumber = fixtures.DefaultAccountNumber
+)
+
+// Builder is a fluent builder of identity.XRHID values. Builders are not safe for
+// concurrent use, but values returned by Build do not share any memory with the builder.
+type Builder struct {
+	id identity.XRHID
+}
+
+func newBuilder(identityType identity.IdentityType) *Builder {
+	id, _ := fixtures.New(identityType)
+	return &Builder{id: id}
+}
+
+// User returns a builder of a "User" identity.
+func User() *Builder {
+	return newBuilder(identity.TypeUser)
+}
+
+// System returns a builder of a "System" identity.
+func System() *Builder {
+	return newBuilder(identity.TypeSystem)
+}
+
+// Associate returns a builder of an "Associate" identity.
+func Associate() *Builder {
+	return newBuilder(identity.TypeAssociate)
+}
+
+// X509 returns a builder of a "X509" identity.
+func X509() *Builder {
+	return newBuilder(identity.TypeX509)
+}
+
+// ServiceAccount returns a builder of a "ServiceAccount" identity.
+func ServiceAccount() *Builder {
+	return newBuilder(identity.TypeServiceAccount)
+}
+
+// All returns a new builder for each supported identity type.
+func All() []*Builder {
+	return []*Builder{User(), System(), Associate(), X509(), ServiceAccount()}
+}
+
+// Type sets the identity type without changing the type-specific fields.
+func (b *Builder) Type(t identity.IdentityType) *Builder {
+	b.id.Identity.Type = string(t)
+	return b
+}
+
+// AuthType sets the auth_type field.
+func (b *Builder) AuthType(t identity.AuthType) *Builder {
+	b.id.Identity.AuthType = string(t)
+	return b
+}
+
+// OrgID sets both the top-level and the internal org_id field.
+func (b *Builder) OrgID(orgID string) *Builder {
+	b.id.Identity.OrgID = orgID
+	b.id.Identity.Internal.OrgID = orgID
+	return b
+}
+
+// AccountNumber sets the account_number field.
+func (b *Builder) AccountNumber(account string) *Builder {
+	b.id.Identity.AccountNumber = account
+	return b
+}
+
+// EmployeeAccountNumber sets the employee_account_number field.
+func (b *Builder) EmployeeAccountNumber(account string) *Builder {
+	b.id.Identity.EmployeeAccountNumber = account
+	return b
+}
+
+// CrossAccess sets the internal cross_access field.
+func (b *Builder) CrossAccess(crossAccess bool) *Builder {
+	b.id.Identity.Internal.CrossAccess = crossAccess
+	return b
+}
+
+// AuthTime sets the internal auth_time field.
+func (b *Builder) AuthTime(t time.Time) *Builder {
+	b.id.Identity.Internal.AuthTime = float64(t.UnixNano()) / float64(time.Second)
+	return b
+}
+
+// Username sets the username of a User or ServiceAccount identity.
+func (b *Builder) Username(username string) *Builder {
+	if b.id.Identity.ServiceAccount != nil {
+		b.id.Identity.ServiceAccount.Username = username
+	} else {
+		b.user().Username = username
+	}
+	return b
+}
+
+// UserID sets the user_id of a User or ServiceAccount identity.
+func (b *Builder) UserID(userID string) *Builder {
+	if b.id.Identity.ServiceAccount != nil {
+		b.id.Identity.ServiceAccount.UserId = userID
+	} else {
+		b.user().UserID = userID
+	}
+	return b
+}
+
+// Email sets the email of a User or Associate identity.
+func (b *Builder) Email(email string) *Builder {
+	if b.id.Identity.Associate != nil {
+		b.id.Identity.Associate.Email = email
+	} else {
+		b.user().Email = email
+	}
+	return b
+}
+
+// OrgAdmin sets the is_org_admin field of the user.
+func (b *Builder) OrgAdmin(orgAdmin bool) *Builder {
+	b.user().OrgAdmin = orgAdmin
+	return b
+}
+
+// InternalUser sets the is_internal field of the user.
+func (b *Builder) InternalUser(internal bool) *Builder {
+	b.user().Internal = internal
+	return b
+}
+
+// CommonName sets the cn field of the system.
+func (b *Builder) CommonName(cn string) *Builder {
+	b.system().CommonName = cn
+	return b
+}
+
+// CertType sets the cert_type field of the system.
+func (b *Builder) CertType(certType string) *Builder {
+	b.system().CertType = certType
+	return b
+}
+
+// ClusterID sets the cluster_id field of the system.
+func (b *Builder) ClusterID(clusterID string) *Builder {
+	b.system().ClusterId = clusterID
+	return b
+}
+
+// OwnerID sets the owner_id field of the system.
+func (b *Builder) OwnerID(ownerID string) *Builder {
+	b.system().OwnerID = ownerID
+	return b
+}
+
+// Roles sets the LDAP roles of the associate.
+func (b *Builder) Roles(roles ...string) *Builder {
+	if b.id.Identity.Associate == nil {
+		b.id.Identity.Associate = &identity.Associate{}
+	}
+	b.id.Identity.Associate.Role = roles
+	return b
+}
+
+// SubjectDN sets the subject_dn field of the x509 certificate.
+func (b *Builder) SubjectDN(dn string) *Builder {
+	b.x509().SubjectDN = dn
+	return b
+}
+
+// IssuerDN sets the issuer_dn field of the x509 certificate.
+func (b *Builder) IssuerDN(dn string) *Builder {
+	b.x509().IssuerDN = dn
+	return b
+}
+
+// ClientID sets the client_id field of the service account.
+func (b *Builder) ClientID(clientID string) *Builder {
+	if b.id.Identity.ServiceAccount == nil {
+		b.id.Identity.ServiceAccount = &identity.ServiceAccount{}
+	}
+	b.id.Identity.ServiceAccount.ClientId = clientID
+	return b
+}
+
+// Entitlement sets entitlement details of the given bundle.
+func (b *Builder) Entitlement(bundle string, entitled, trial bool) *Builder {
+	if b.id.Entitlements == nil {
+		b.id.Entitlements = make(map[string]identity.ServiceDetails)
+	}
+	b.id.Entitlements[bundle] = identity.ServiceDetails{IsEntitled: entitled, IsTrial: trial}
+	return b
+}
+
+// Modify calls fn with the identity being built for changes not covered by the setters.
+func (b *Builder) Modify(fn func(id *identity.XRHID)) *Builder {
+	fn(&b.id)
+	return b
+}
+
+// Build returns a copy of the identity.
+func (b *Builder) Build() identity.XRHID {
+	// a JSON round-trip is the simplest deep copy which is fine for tests
+	var id identity.XRHID
+	if err := json.Unmarshal(b.JSON(), &id); err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// JSON returns the identity encoded as JSON.
+func (b *Builder) JSON() []byte {
+	buf, err := json.Marshal(b.id)
+	if err != nil {
+		panic(err)
+	}
+	return buf
+}
+
+// Header returns the identity as a base64 JSON encoded string suitable for the
+// X-Rh-Identity header.
+func (b *Builder) Header() string {
+	return base64.StdEncoding.EncodeToString(b.JSON())
+}
+
+// Context returns a copy of the context with both parsed and raw identity stored
+// via identity.WithIdentity and identity.WithRawIdentity.
+func (b *Builder) Context(ctx context.Context) context.Context {
+	ctx = identity.WithIdentity(ctx, b.Build())
+	return identity.WithRawIdentity(ctx, b.Header())
+}
+
+// Request returns a new test request with the X-Rh-Identity header set and the
+// identity stored in the request context. It panics on invalid arguments, see
+// httptest.NewRequest.
+func (b *Builder) Request(method, target string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	r.Header.Set("X-Rh-Identity", b.Header())
+	return r.WithContext(b.Context(r.Context()))
+}
+
+func (b *Builder) user() *identity.User {
+	if b.id.Identity.User == nil {
+		b.id.Identity.User = &identity.User{}
+	}
+	return b.id.Identity.User
+}
+
+func (b *Builder) system() *identity.System {
+	if b.id.Identity.System == nil {
+		b.id.Identity.System = &identity.System{}
+	}
+	return b.id.Identity.System
+}
+
+func (b *Builder) x509() *identity.X509 {
+	if b.id.Identity.X509 == nil {
+		b.id.Identity.X509 = &identity.X509{}
+	}
+	return b.id.Identity.X509
+}
@@ -0,0 +1,67 @@
+package identitytest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestIdentityTest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "IdentityTest Suite")
+}
+
+var _ = Describe("Builders", func() {
+	Context("With default fixtures", func() {
+		It("should pass DecodeAndCheckIdentity", func() {
+			for _, b := range identitytest.All() {
+				id, err := identity.DecodeAndCheckIdentity(b.Header())
+				Expect(err).To(BeNil())
+				Expect(id).To(Equal(b.Build()))
+				Expect(id.Identity.OrgID).To(Equal(identitytest.DefaultOrgID))
+			}
+		})
+
+		It("should pass the identity middleware", func() {
+			for _, b := range identitytest.All() {
+				rr := httptest.NewRecorder()
+				handler := identity.EnforceIdentityWithLogger(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+				handler.ServeHTTP(rr, b.Request("GET", "/api/app/v1/"))
+				Expect(rr.Code).To(Equal(200))
+			}
+		})
+	})
+
+	Context("With fluent setters", func() {
+		It("should set type-specific fields", func() {
+			id := identitytest.ServiceAccount().Username("sa").UserID("42").OrgID("1").Build()
+			Expect(id.Identity.ServiceAccount.Username).To(Equal("sa"))
+			Expect(id.Identity.ServiceAccount.UserId).To(Equal("42"))
+			Expect(id.Identity.User).To(BeNil())
+			Expect(id.Identity.OrgID).To(Equal("1"))
+			Expect(id.Identity.Internal.OrgID).To(Equal("1"))
+		})
+
+		It("should not share memory between built values", func() {
+			b := identitytest.Associate().Roles("a")
+			first := b.Build()
+			first.Identity.Associate.Role[0] = "changed"
+			Expect(b.Build().Identity.Associate.Role).To(Equal([]string{"a"}))
+		})
+
+		It("should populate the context", func() {
+			ctx := identitytest.User().OrgAdmin(true).Context(context.Background())
+			Expect(identity.GetIdentity(ctx).Identity.User.OrgAdmin).To(BeTrue())
+			id, err := identity.DecodeAndCheckIdentity(identity.GetRawIdentity(ctx))
+			Expect(err).To(BeNil())
+			Expect(id.Identity.User.OrgAdmin).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,155 @@
+package identity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrIdentityLimit = errors.New("x-rh-identity header exceeds limits")
+
+// Limits harden identity decoding against oversized or deeply nested headers. Zero
+// value of each field means no limit.
+type Limits struct {
+	// MaxEncodedLength is the maximum length of the base64 encoded header.
+	MaxEncodedLength int
+
+	// MaxDecodedBytes is the maximum length of the decoded JSON document.
+	MaxDecodedBytes int
+
+	// MaxEntitlements is the maximum number of entitlements.
+	MaxEntitlements int
+
+	// MaxStringLength is the maximum length of any JSON string, keys included.
+	MaxStringLength int
+
+	// MaxDepth is the maximum nesting depth of JSON objects and arrays.
+	MaxDepth int
+}
+
+// DefaultLimits returns limits which are generous for all identities seen in the
+// platform while still preventing excessive allocations.
+func DefaultLimits() *Limits {
+	return &Limits{
+		MaxEncodedLength: 16 * 1024,
+		MaxDecodedBytes:  12 * 1024,
+		MaxEntitlements:  128,
+		MaxStringLength:  2048,
+		MaxDepth:         8,
+	}
+}
+
+// LimitError is returned when a header exceeds one of the limits, it wraps
+// ErrIdentityLimit.
+type LimitError struct {
+	// Limit is the name of the exceeded limit, e.g. "encoded length".
+	Limit string
+
+	// Max is the configured limit.
+	Max int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s over %d", ErrIdentityLimit.Error(), e.Limit, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrIdentityLimit
+}
+
+// HeaderTooLarge returns true when the raw header length was exceeded, the
+// middleware responds with HTTP code 431 in this case and 400 otherwise.
+func (e *LimitError) HeaderTooLarge() bool {
+	return e.Limit == limitEncodedLength
+}
+
+const (
+	limitEncodedLength = "encoded length"
+	limitDecodedBytes  = "decoded bytes"
+	limitEntitlements  = "entitlement count"
+	limitStringLength  = "string length"
+	limitDepth         = "nesting depth"
+)
+
+func (l *Limits) checkEncoded(header string) error {
+	if l != nil && l.MaxEncodedLength > 0 && len(header) > l.MaxEncodedLength {
+		return &LimitError{Limit: limitEncodedLength, Max: l.MaxEncodedLength}
+	}
+	return nil
+}
+
+// checkDecoded verifies size and structure of the JSON document by streaming its
+// tokens, so oversized documents are rejected before any value is allocated by
+// unmarshalling. Syntax errors are ignored here and reported by unmarshalling.
+func (l *Limits) checkDecoded(data []byte) error {
+	if l == nil {
+		return nil
+	}
+
+	if l.MaxDecodedBytes > 0 && len(data) > l.MaxDecodedBytes {
+		return &LimitError{Limit: limitDecodedBytes, Max: l.MaxDecodedBytes}
+	}
+
+	if l.MaxDepth <= 0 && l.MaxStringLength <= 0 && l.MaxEntitlements <= 0 {
+		return nil
+	}
+
+	type frame struct {
+		object  bool
+		keyNext bool
+		key     string
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var stack []frame
+	entitlements := 0
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		// closing delimiters end the current value of the parent object
+		if tok == json.Delim('}') || tok == json.Delim(']') {
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 && stack[len(stack)-1].object {
+				stack[len(stack)-1].keyNext = true
+			}
+			continue
+		}
+
+		if str, ok := tok.(string); ok && l.MaxStringLength > 0 && len(str) > l.MaxStringLength {
+			return &LimitError{Limit: limitStringLength, Max: l.MaxStringLength}
+		}
+
+		var top *frame
+		if len(stack) > 0 {
+			top = &stack[len(stack)-1]
+		}
+
+		if top != nil && top.object && top.keyNext {
+			top.keyNext = false
+			top.key, _ = tok.(string)
+			// keys of the root "entitlements" object
+			if len(stack) == 2 && stack[0].key == "entitlements" {
+				entitlements++
+				if l.MaxEntitlements > 0 && entitlements > l.MaxEntitlements {
+					return &LimitError{Limit: limitEntitlements, Max: l.MaxEntitlements}
+				}
+			}
+			continue
+		}
+
+		if tok == json.Delim('{') || tok == json.Delim('[') {
+			if l.MaxDepth > 0 && len(stack) >= l.MaxDepth {
+				return &LimitError{Limit: limitDepth, Max: l.MaxDepth}
+			}
+			stack = append(stack, frame{object: tok == json.Delim('{'), keyNext: true})
+		} else if top != nil && top.object {
+			top.keyNext = true
+		}
+	}
+}
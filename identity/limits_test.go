@@ -0,0 +1,88 @@
+package identity_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limits", func() {
+	decode := func(limits identity.Limits, json string) error {
+		_, err := identity.DecodeOptions{Limits: &limits}.DecodeAndCheckIdentity(getBase64(json))
+		return err
+	}
+
+	It("should accept all fixtures with default limits", func() {
+		opts := identity.DecodeOptions{Limits: identity.DefaultLimits()}
+		for _, json := range validJson {
+			_, err := opts.DecodeAndCheckIdentity(getBase64(json))
+			Expect(err).To(BeNil())
+		}
+	})
+
+	It("should limit the encoded length", func() {
+		err := decode(identity.Limits{MaxEncodedLength: 100}, exampleHeader)
+		Expect(errors.Is(err, identity.ErrIdentityLimit)).To(BeTrue())
+		Expect(err.Error()).To(Equal("x-rh-identity header exceeds limits: encoded length over 100"))
+	})
+
+	It("should limit the decoded length", func() {
+		err := decode(identity.Limits{MaxDecodedBytes: 100}, exampleHeader)
+		Expect(err).To(MatchError(&identity.LimitError{Limit: "decoded bytes", Max: 100}))
+	})
+
+	It("should limit nesting depth", func() {
+		Expect(decode(identity.Limits{MaxDepth: 3}, exampleHeader)).To(Succeed())
+		err := decode(identity.Limits{MaxDepth: 3}, `{"identity": {"type": "User", "org_id": "1", "x": {"y": [1]}}}`)
+		Expect(err).To(MatchError(&identity.LimitError{Limit: "nesting depth", Max: 3}))
+	})
+
+	It("should limit string length", func() {
+		err := decode(identity.Limits{MaxStringLength: 10}, exampleHeader)
+		Expect(err).To(MatchError(&identity.LimitError{Limit: "string length", Max: 10}))
+	})
+
+	It("should limit the number of entitlements", func() {
+		Expect(decode(identity.Limits{MaxEntitlements: 2}, exampleHeader)).To(Succeed())
+
+		var bundles []string
+		for i := 0; i < 3; i++ {
+			bundles = append(bundles, fmt.Sprintf(`"b%d": {"is_entitled": true}`, i))
+		}
+		json := `{"entitlements": {` + strings.Join(bundles, ",") + `}, "identity": {"type": "User", "org_id": "1"}}`
+		err := decode(identity.Limits{MaxEntitlements: 2}, json)
+		Expect(err).To(MatchError(&identity.LimitError{Limit: "entitlement count", Max: 2}))
+	})
+
+	It("should report invalid JSON as unmarshal errors", func() {
+		err := decode(*identity.DefaultLimits(), exampleHeader+"}")
+		Expect(errors.Is(err, identity.ErrUnmarshalIdentity)).To(BeTrue())
+	})
+
+	Context("With the middleware", func() {
+		serve := func(limits identity.Limits) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			handler := identity.EnforceIdentityWithOptions(identity.WithDecodeOptions(identity.DecodeOptions{Limits: &limits}))
+			handler(GetTestHandler(false)).ServeHTTP(rr, identitytest.User().Request("GET", "/"))
+			return rr
+		}
+
+		It("should respond 431 for long headers", func() {
+			rr := serve(identity.Limits{MaxEncodedLength: 10})
+			Expect(rr.Code).To(Equal(431))
+			Expect(rr.Body.String()).To(Equal("Request Header Fields Too Large: x-rh-identity header exceeds limits: encoded length over 10\n"))
+		})
+
+		It("should respond 400 for structure limits", func() {
+			rr := serve(identity.Limits{MaxEntitlements: 0, MaxDepth: 1})
+			Expect(rr.Code).To(Equal(400))
+		})
+	})
+})
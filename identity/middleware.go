@@ -0,0 +1,228 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Option configures the middleware created by EnforceIdentityWithOptions.
+type Option func(m *middleware)
+
+// WithErrorFunc sets the logging callback, no logging is performed by default.
+func WithErrorFunc(logger ErrorFunc) Option {
+	return func(m *middleware) {
+		m.logger = logger
+	}
+}
+
+// WithPolicies appends policies which are checked in order after the base policy.
+func WithPolicies(policies ...Policy) Option {
+	return func(m *middleware) {
+		m.policies = append(m.policies, policies...)
+	}
+}
+
+// WithErrorResponder sets the function writing error responses, PlainTextResponder
+// is used by default. The responder is also used by middlewares which check identity
+// later in the chain (e.g. EnforceEntitlements or Require).
+func WithErrorResponder(responder ErrorResponder) Option {
+	return func(m *middleware) {
+		m.responder = responder
+	}
+}
+
+// EnforceIdentityWithOptions extracts, checks and places the X-Rh-Identity header into the
+// request context. If the Identity is invalid, the request will be aborted with HTTP code
+// 400 (or 431 when the header is over the configured length limit), when one of the
+// configured policies rejects the identity with HTTP code 403 (or 401 when
+// re-authentication is required).
+func EnforceIdentityWithOptions(opts ...Option) func(next http.Handler) http.Handler {
+	return newMiddleware(opts).handler
+}
+
+// OptionalIdentity works like EnforceIdentityWithOptions but it never rejects requests.
+// When the X-Rh-Identity header is present, the identity is decoded, checked and stored
+// in the context. When decoding or one of the policies fail, the error is stored in
+// the context instead and can be retrieved via GetIdentityError. Use GetIdentityOK
+// to find out whether a valid identity was found. The error responder option is ignored.
+func OptionalIdentity(opts ...Option) func(next http.Handler) http.Handler {
+	m := newMiddleware(opts)
+	m.optional = true
+	return m.handler
+}
+
+// middleware holds configuration of the identity enforcing middleware
+type middleware struct {
+	logger     ErrorFunc
+	policies   []Policy
+	responder  ErrorResponder
+	exemptions []Exemption
+	decode     DecodeOptions
+	observer   Observer
+	optional   bool
+}
+
+func newMiddleware(opts []Option) *middleware {
+	m := &middleware{}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.logger == nil {
+		m.logger = noopErrorFunc
+	}
+	if m.responder == nil {
+		m.responder = PlainTextResponder
+	}
+	return m
+}
+
+func (m *middleware) handler(next http.Handler) http.Handler {
+	return &enforceHandler{m: m, next: next}
+}
+
+// enforceHandler is the handler created by the identity middleware, it is a named
+// type so the route report can recognize it.
+type enforceHandler struct {
+	m    *middleware
+	next http.Handler
+}
+
+func (h *enforceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m, next := h.m, h.next
+	start := time.Now()
+	// route policies use the configured logger and responder also for exempted and
+	// anonymous requests
+	r = r.WithContext(context.WithValue(r.Context(), middlewareKey, m))
+	if name, ok := m.matchExemption(r); ok {
+		m.observe(start, OutcomeExempt, nil, nil)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), exemptionKey, name)))
+		return
+	}
+
+	id := r.Header.Get("X-Rh-Identity")
+	if m.optional && id == "" {
+		m.observe(start, OutcomeAnonymous, nil, nil)
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	ctx, err := m.decode.DecodeIdentityCtx(r.Context(), id)
+	if err != nil {
+		m.observe(start, ErrorCode(err), err, nil)
+		m.fail(w, r, next, id, decodeErrorStatus(err), err)
+		return
+	}
+	trackCrossAccess(ctx)
+
+	if len(m.policies) > 0 || m.observer != nil {
+		xrhid := GetIdentity(ctx)
+		if err := CheckPolicies(&xrhid, m.policies...); err != nil {
+			m.observe(start, ErrorCode(err), err, &xrhid)
+			m.fail(w, r, next, id, policyErrorStatus(err), err)
+			return
+		}
+		m.observe(start, OutcomeOK, nil, &xrhid)
+	}
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// decodeErrorStatus returns HTTP code 431 for headers over the length limit, 503 when
+// the org_id resolver failed and 400 for other decoding errors
+func decodeErrorStatus(err error) int {
+	var le *LimitError
+	if errors.As(err, &le) && le.HeaderTooLarge() {
+		return http.StatusRequestHeaderFieldsTooLarge
+	}
+	if errors.Is(err, ErrResolveOrgID) {
+		return http.StatusServiceUnavailable
+	}
+	return 400
+}
+
+// policyErrorStatus returns HTTP code 401 when re-authentication is required and 403
+// for other policy errors
+func policyErrorStatus(err error) int {
+	if errors.Is(err, ErrReauthenticationRequired) {
+		return http.StatusUnauthorized
+	}
+	return 403
+}
+
+// failure is the error stored in the context in optional mode together with the HTTP
+// code the request would have been rejected with
+type failure struct {
+	err    error
+	status int
+}
+
+// fail logs the error and either rejects the request or, in optional mode, stores
+// the error in the context and passes the request on.
+func (m *middleware) fail(w http.ResponseWriter, r *http.Request, next http.Handler, rawID string, status int, err error) {
+	m.logger(r.Context(), rawID, http.StatusText(status)+": "+err.Error())
+	if m.optional {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), errorKey, failure{err: err, status: status})))
+		return
+	}
+	m.responder(w, r, status, err)
+}
+
+// enforcePolicies returns middleware checking policies against the identity which is
+// already present in the request context. Requests without identity in the context
+// (exempted, anonymous or not processed by the identity middleware) are rejected with
+// HTTP code 400 before any policy is evaluated. The logger and error responder
+// configured on the enforcing middleware are used unless logger is given.
+func enforcePolicies(logger ErrorFunc, policies ...Policy) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return &policyHandler{logger: logger, policies: policies, next: next}
+	}
+}
+
+// policyHandler is the handler created by enforcePolicies, it is a named type so
+// the route report can recognize it.
+type policyHandler struct {
+	logger   ErrorFunc
+	policies []Policy
+	next     http.Handler
+}
+
+func (h *policyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, ok := GetIdentityOK(ctx)
+	if !ok {
+		if f, ok := ctx.Value(errorKey).(failure); ok {
+			h.reject(w, r, f.status, f.err)
+		} else {
+			h.reject(w, r, decodeErrorStatus(ErrMissingIdentity), ErrMissingIdentity)
+		}
+		return
+	}
+
+	if err := CheckPolicies(&id, h.policies...); err != nil {
+		h.reject(w, r, policyErrorStatus(err), err)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+func (h *policyHandler) reject(w http.ResponseWriter, r *http.Request, status int, err error) {
+	ctx := r.Context()
+	m := middlewareFromContext(ctx)
+	logger := h.logger
+	if logger == nil {
+		logger = m.logger
+	}
+	logger(ctx, GetRawIdentity(ctx), http.StatusText(status)+": "+err.Error())
+	m.responder(w, r, status, err)
+}
+
+// middlewareFromContext returns configuration of the enforcing middleware stored in
+// the context or the default configuration
+func middlewareFromContext(ctx context.Context) *middleware {
+	if m, ok := ctx.Value(middlewareKey).(*middleware); ok {
+		return m
+	}
+	return newMiddleware(nil)
+}
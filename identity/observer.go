@@ -0,0 +1,172 @@
+package identity
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+)
+
+// Outcomes of requests processed by the identity middleware which are not errors,
+// errors are reported with their ErrorCode.
+const (
+	OutcomeOK        = "ok"
+	OutcomeExempt    = "exempt"
+	OutcomeAnonymous = "anonymous"
+)
+
+// Observation describes a single request processed by the identity middleware.
+type Observation struct {
+	// Outcome is OutcomeOK, OutcomeExempt, OutcomeAnonymous (OptionalIdentity without
+	// header) or the ErrorCode of the decoding or policy error.
+	Outcome string
+
+	// Err is the decoding or policy error, nil for successful outcomes.
+	Err error
+
+	// IdentityType is the type of the decoded identity, empty when decoding failed.
+	// Types which are not Known are reported as "unknown", so the set of values is
+	// bounded regardless of the header contents.
+	IdentityType IdentityType
+
+	// AuthType is the auth type of the decoded identity, empty when decoding failed.
+	// Auth types which are not Known are reported as "unknown".
+	AuthType AuthType
+
+	// Duration is the time spent by decoding and checking the identity.
+	Duration time.Duration
+}
+
+// Observer receives metrics of requests processed by the identity middleware. It is
+// called synchronously for every request and must be safe for concurrent use.
+type Observer interface {
+	Observe(o Observation)
+}
+
+// ObserverFunc is an adapter to allow the use of ordinary functions as observers.
+type ObserverFunc func(o Observation)
+
+// Observe calls f(o).
+func (f ObserverFunc) Observe(o Observation) {
+	f(o)
+}
+
+// WithObserver sets the observer of the middleware, no metrics are collected by default.
+func WithObserver(observer Observer) Option {
+	return func(m *middleware) {
+		m.observer = observer
+	}
+}
+
+// observe reports the outcome to the observer when configured
+func (m *middleware) observe(start time.Time, outcome string, err error, id *XRHID) {
+	if m.observer == nil {
+		return
+	}
+	o := Observation{Outcome: outcome, Err: err, Duration: time.Since(start)}
+	if id != nil {
+		o.IdentityType = id.Identity.IdentityType()
+		o.AuthType = id.Identity.AuthenticationType()
+	}
+	m.observer.Observe(o.bounded())
+}
+
+// unknownLabel replaces values which are not known to this package
+const unknownLabel = "unknown"
+
+// bounded returns the observation with unknown identity and auth types replaced, so
+// values from headers cannot create an unbounded number of metric labels
+func (o Observation) bounded() Observation {
+	if o.IdentityType != "" && !o.IdentityType.Known() {
+		o.IdentityType = unknownLabel
+	}
+	if o.AuthType != "" && !o.AuthType.Known() {
+		o.AuthType = unknownLabel
+	}
+	return o
+}
+
+// durationBuckets are upper bounds of the duration histogram of ExpvarObserver
+var durationBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+// ExpvarObserver is an Observer collecting counters in an expvar.Map with the
+// following keys:
+//
+//	outcomes         counts by outcome
+//	identity_types   counts by identity type of decoded identities
+//	auth_types       counts by auth type of decoded identities
+//	duration_count   number of observations
+//	duration_seconds sum of durations in seconds
+//	duration_buckets cumulative counts of durations less or equal to the bucket
+//	                 in seconds ("le_0.0001", "le_0.001", "le_0.01", "le_0.1")
+type ExpvarObserver struct {
+	vars          *expvar.Map
+	outcomes      *expvar.Map
+	identityTypes *expvar.Map
+	authTypes     *expvar.Map
+	count         *expvar.Int
+	seconds       *expvar.Float
+	buckets       *expvar.Map
+}
+
+// NewExpvarObserver returns an observer publishing its counters under the given
+// name, the counters are not published when name is empty. Like expvar.Publish, the
+// function panics when the name is already registered.
+func NewExpvarObserver(name string) *ExpvarObserver {
+	o := &ExpvarObserver{
+		vars:          new(expvar.Map).Init(),
+		outcomes:      new(expvar.Map).Init(),
+		identityTypes: new(expvar.Map).Init(),
+		authTypes:     new(expvar.Map).Init(),
+		count:         new(expvar.Int),
+		seconds:       new(expvar.Float),
+		buckets:       new(expvar.Map).Init(),
+	}
+	o.vars.Set("outcomes", o.outcomes)
+	o.vars.Set("identity_types", o.identityTypes)
+	o.vars.Set("auth_types", o.authTypes)
+	o.vars.Set("duration_count", o.count)
+	o.vars.Set("duration_seconds", o.seconds)
+	o.vars.Set("duration_buckets", o.buckets)
+	for _, b := range durationBuckets {
+		o.buckets.Add(bucketKey(b), 0)
+	}
+
+	if name != "" {
+		expvar.Publish(name, o.vars)
+	}
+	return o
+}
+
+// Map returns the map with all counters.
+func (e *ExpvarObserver) Map() *expvar.Map {
+	return e.vars
+}
+
+// Observe implements Observer.
+func (e *ExpvarObserver) Observe(o Observation) {
+	o = o.bounded()
+	e.outcomes.Add(o.Outcome, 1)
+	if o.IdentityType != "" {
+		e.identityTypes.Add(string(o.IdentityType), 1)
+	}
+	if o.AuthType != "" {
+		e.authTypes.Add(string(o.AuthType), 1)
+	}
+
+	e.count.Add(1)
+	e.seconds.Add(o.Duration.Seconds())
+	for _, b := range durationBuckets {
+		if o.Duration <= b {
+			e.buckets.Add(bucketKey(b), 1)
+		}
+	}
+}
+
+func bucketKey(b time.Duration) string {
+	return "le_" + strconv.FormatFloat(b.Seconds(), 'g', -1, 64)
+}
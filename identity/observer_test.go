@@ -0,0 +1,93 @@
+package identity_test
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Observer", func() {
+	var (
+		mu           sync.Mutex
+		observations []identity.Observation
+	)
+
+	observer := identity.ObserverFunc(func(o identity.Observation) {
+		mu.Lock()
+		defer mu.Unlock()
+		observations = append(observations, o)
+	})
+
+	BeforeEach(func() {
+		observations = nil
+	})
+
+	serve := func(mw func(next http.Handler) http.Handler, req *http.Request) {
+		mw(GetTestHandler(true)).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	It("should observe outcomes", func() {
+		mw := identity.EnforceIdentityWithOptions(
+			identity.WithObserver(observer),
+			identity.WithPolicies(identity.RequireType(identity.TypeUser)),
+			identity.WithExemptions(identity.ExemptPaths("/healthz")))
+
+		serve(mw, identitytest.User().Request("GET", "/"))
+		serve(mw, identitytest.System().Request("GET", "/"))
+		serve(mw, httptest.NewRequest("GET", "/", nil))
+		serve(mw, httptest.NewRequest("GET", "/healthz", nil))
+		serve(identity.OptionalIdentity(identity.WithObserver(observer)), httptest.NewRequest("GET", "/", nil))
+
+		Expect(observations).To(HaveLen(5))
+		Expect(observations[0].Outcome).To(Equal(identity.OutcomeOK))
+		Expect(observations[0].IdentityType).To(Equal(identity.TypeUser))
+		Expect(observations[0].AuthType).To(Equal(identity.AuthTypeJWT))
+		Expect(observations[0].Err).To(BeNil())
+
+		Expect(observations[1].Outcome).To(Equal("disallowed_identity_type"))
+		Expect(observations[1].IdentityType).To(Equal(identity.TypeSystem))
+		Expect(errors.Is(observations[1].Err, identity.ErrPolicyIdentityType)).To(BeTrue())
+
+		Expect(observations[2].Outcome).To(Equal("missing_identity"))
+		Expect(observations[2].IdentityType).To(BeEmpty())
+		Expect(observations[3].Outcome).To(Equal(identity.OutcomeExempt))
+		Expect(observations[4].Outcome).To(Equal(identity.OutcomeAnonymous))
+	})
+
+	It("should collect expvar counters", func() {
+		o := identity.NewExpvarObserver("identity_observer_test")
+		Expect(expvar.Get("identity_observer_test")).To(Equal(o.Map()))
+
+		mw := identity.EnforceIdentityWithOptions(identity.WithObserver(o))
+		serve(mw, identitytest.User().Request("GET", "/"))
+		serve(mw, identitytest.ServiceAccount().Request("GET", "/"))
+		serve(mw, httptest.NewRequest("GET", "/", nil))
+		for _, t := range []string{"Robot1", "Robot2"} {
+			serve(mw, identitytest.User().Type(identity.IdentityType(t)).AuthType(identity.AuthType(t)).Request("GET", "/"))
+		}
+		o.Observe(identity.Observation{Outcome: "ok", IdentityType: "Robot3", AuthType: "robot-auth"})
+
+		var vars struct {
+			Outcomes        map[string]int `json:"outcomes"`
+			IdentityTypes   map[string]int `json:"identity_types"`
+			AuthTypes       map[string]int `json:"auth_types"`
+			DurationCount   int            `json:"duration_count"`
+			DurationBuckets map[string]int `json:"duration_buckets"`
+		}
+		Expect(json.Unmarshal([]byte(o.Map().String()), &vars)).To(Succeed())
+		Expect(vars.Outcomes).To(Equal(map[string]int{"ok": 5, "missing_identity": 1}))
+		Expect(vars.IdentityTypes).To(Equal(map[string]int{"User": 1, "ServiceAccount": 1, "unknown": 3}))
+		Expect(vars.AuthTypes).To(Equal(map[string]int{"jwt-auth": 2, "unknown": 3}))
+		Expect(vars.DurationCount).To(Equal(6))
+		Expect(vars.DurationBuckets).To(HaveKeyWithValue("le_0.1", 6))
+	})
+})
@@ -0,0 +1,68 @@
+package identity_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OptionalIdentity", func() {
+	var (
+		req    *http.Request
+		rr     *httptest.ResponseRecorder
+		found  bool
+		id     identity.XRHID
+		decErr error
+	)
+
+	serve := func(opts ...identity.Option) {
+		handler := identity.OptionalIdentity(opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, found = identity.GetIdentityOK(r.Context())
+			decErr = identity.GetIdentityError(r.Context())
+		}))
+		handler.ServeHTTP(rr, req)
+	}
+
+	BeforeEach(func() {
+		req, _ = http.NewRequest("GET", "/api/entitlements/v1/services/", nil)
+		rr = httptest.NewRecorder()
+		found, decErr = false, nil
+	})
+
+	It("should pass requests without identity", func() {
+		serve()
+		Expect(rr.Code).To(Equal(200))
+		Expect(found).To(BeFalse())
+		Expect(decErr).To(BeNil())
+	})
+
+	It("should store a valid identity", func() {
+		req.Header.Set("x-rh-identity", getBase64(exampleHeader))
+		serve()
+		Expect(rr.Code).To(Equal(200))
+		Expect(found).To(BeTrue())
+		Expect(id.Identity.OrgID).To(Equal("1979710"))
+		Expect(decErr).To(BeNil())
+	})
+
+	It("should store decoding errors", func() {
+		req.Header.Set("x-rh-identity", "=invalid")
+		serve()
+		Expect(rr.Code).To(Equal(200))
+		Expect(found).To(BeFalse())
+		Expect(errors.Is(decErr, identity.ErrDecodeIdentity)).To(BeTrue())
+	})
+
+	It("should store policy errors", func() {
+		req.Header.Set("x-rh-identity", getBase64(exampleHeader))
+		serve(identity.WithPolicies(identity.RequireType("System")))
+		Expect(rr.Code).To(Equal(200))
+		Expect(found).To(BeFalse())
+		Expect(errors.Is(decErr, identity.ErrPolicyIdentityType)).To(BeTrue())
+	})
+})
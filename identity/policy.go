@@ -0,0 +1,146 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Policy is a semantic identity check. Check returns nil when the identity is
+// acceptable or an error describing why it was rejected.
+//
+// Policies are evaluated in order by EnforceIdentityWithPolicies after the
+// identity was decoded and passed the base policy, or by Require for identities
+// which are already stored in the request context. Built-in policies also
+// implement fmt.Stringer which returns a short human-readable description.
+type Policy interface {
+	Check(id *XRHID) error
+}
+
+// PolicyFunc is an adapter to allow the use of ordinary functions as policies.
+type PolicyFunc func(id *XRHID) error
+
+// Check calls f(id).
+func (f PolicyFunc) Check(id *XRHID) error {
+	return f(id)
+}
+
+// namedPolicy is a policy with a description, all built-in policies use it.
+type namedPolicy struct {
+	name string
+	fn   func(id *XRHID) error
+}
+
+func (p namedPolicy) Check(id *XRHID) error {
+	return p.fn(id)
+}
+
+func (p namedPolicy) String() string {
+	return p.name
+}
+
+var (
+	ErrPolicyIdentityType = errors.New("x-rh-identity header has a disallowed type")
+	ErrPolicyAuthType     = errors.New("x-rh-identity header has a disallowed auth_type")
+	ErrPolicyMissingUser  = errors.New("x-rh-identity header is missing user details")
+	ErrPolicyNotOrgAdmin  = errors.New("x-rh-identity user is not an org admin")
+)
+
+// BasePolicy returns the policy which is always performed by DecodeAndCheckIdentity.
+// It is useful when identity values are constructed manually.
+func BasePolicy() Policy {
+	return namedPolicy{name: "BasePolicy", fn: checkBasePolicy}
+}
+
+// RequireType returns a policy that accepts only identities with one of the given types.
+func RequireType(types ...IdentityType) Policy {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+
+	return namedPolicy{
+		name: fmt.Sprintf("RequireType(%s)", strings.Join(names, ", ")),
+		fn: func(id *XRHID) error {
+			for _, t := range types {
+				if id.Identity.IdentityType() == t {
+					return nil
+				}
+			}
+			return fmt.Errorf("%w: %q", ErrPolicyIdentityType, id.Identity.Type)
+		},
+	}
+}
+
+// RequireAuthType returns a policy that accepts only identities with one of the given auth types.
+func RequireAuthType(authTypes ...AuthType) Policy {
+	names := make([]string, len(authTypes))
+	for i, t := range authTypes {
+		names[i] = string(t)
+	}
+
+	return namedPolicy{
+		name: fmt.Sprintf("RequireAuthType(%s)", strings.Join(names, ", ")),
+		fn: func(id *XRHID) error {
+			for _, t := range authTypes {
+				if id.Identity.AuthenticationType() == t {
+					return nil
+				}
+			}
+			return fmt.Errorf("%w: %q", ErrPolicyAuthType, id.Identity.AuthType)
+		},
+	}
+}
+
+// RequireOrgID returns a policy that accepts only identities with org_id. Unlike the
+// base policy, no exception is made for Associate and X509 identities.
+func RequireOrgID() Policy {
+	return namedPolicy{
+		name: "RequireOrgID",
+		fn: func(id *XRHID) error {
+			if id.Identity.OrgID == "" && id.Identity.Internal.OrgID == "" {
+				return ErrInvalidOrgIdIdentity
+			}
+			return nil
+		},
+	}
+}
+
+// RequireNonEmptyUser returns a policy that accepts only identities with the "user" field
+// present and both username and user_id set.
+func RequireNonEmptyUser() Policy {
+	return namedPolicy{
+		name: "RequireNonEmptyUser",
+		fn: func(id *XRHID) error {
+			u := id.Identity.User
+			if u == nil || u.Username == "" || u.UserID == "" {
+				return ErrPolicyMissingUser
+			}
+			return nil
+		},
+	}
+}
+
+// OrgAdmin returns a policy that accepts only users who are organization administrators.
+func OrgAdmin() Policy {
+	return namedPolicy{
+		name: "OrgAdmin",
+		fn: func(id *XRHID) error {
+			if !id.Identity.IsUser() || id.Identity.User == nil || !id.Identity.User.OrgAdmin {
+				return ErrPolicyNotOrgAdmin
+			}
+			return nil
+		},
+	}
+}
+
+// CheckPolicies evaluates policies in order and returns the first error or nil
+// when all policies accepted the identity.
+func CheckPolicies(id *XRHID, policies ...Policy) error {
+	for _, p := range policies {
+		if err := p.Check(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
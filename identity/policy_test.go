@@ -0,0 +1,89 @@
+package identity_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func boilerWithPolicies(req *http.Request, expectedStatusCode int, expectedBody string, policies ...identity.Policy) {
+	rr := httptest.NewRecorder()
+
+	handler := identity.EnforceIdentityWithPolicies(noopLogger, policies...)
+	handler(GetTestHandler(expectedStatusCode == 200)).ServeHTTP(rr, req)
+
+	Expect(rr.Code).To(Equal(expectedStatusCode))
+	Expect(rr.Body.String()).To(Equal(expectedBody))
+}
+
+var _ = Describe("Policy", func() {
+	var req *http.Request
+
+	BeforeEach(func() {
+		r, err := http.NewRequest("GET", "/api/entitlements/v1/services/", nil)
+		if err != nil {
+			panic("Test error unable to get a NewRequest")
+		}
+		req = r
+	})
+
+	Context("With built-in policies", func() {
+		It("should accept matching identity types", func() {
+			req.Header.Set("x-rh-identity", getBase64(exampleHeader))
+			boilerWithPolicies(req, 200, "", identity.RequireType("ServiceAccount", "User"))
+		})
+
+		It("should reject other identity types with 403", func() {
+			req.Header.Set("x-rh-identity", getBase64(serviceAccountIdentity))
+			boilerWithPolicies(req, 403, "Forbidden: x-rh-identity header has a disallowed type: \"ServiceAccount\"\n", identity.RequireType("User"))
+		})
+
+		It("should reject other auth types with 403", func() {
+			req.Header.Set("x-rh-identity", getBase64(exampleHeader))
+			boilerWithPolicies(req, 403, "Forbidden: x-rh-identity header has a disallowed auth_type: \"jwt-auth\"\n", identity.RequireAuthType("cert-auth"))
+		})
+
+		It("should require org_id even for associates", func() {
+			req.Header.Set("x-rh-identity", getBase64(`{ "identity": {"type": "Associate"} }`))
+			boilerWithPolicies(req, 403, "Forbidden: x-rh-identity header has an invalid or missing org_id\n", identity.RequireOrgID())
+		})
+
+		It("should require user details", func() {
+			req.Header.Set("x-rh-identity", getBase64(serviceAccountIdentity))
+			boilerWithPolicies(req, 403, "Forbidden: x-rh-identity header is missing user details\n", identity.RequireNonEmptyUser())
+		})
+
+		It("should still perform the base policy", func() {
+			req.Header.Set("x-rh-identity", getBase64(`{ "identity": {"account_number": "540155", "type": "User", "internal": {} } }`))
+			boilerWithPolicies(req, 400, "Bad Request: x-rh-identity header has an invalid or missing org_id\n", identity.RequireType("User"))
+		})
+	})
+
+	Context("With a chain of policies", func() {
+		It("should stop at the first failing policy", func() {
+			called := false
+			last := identity.PolicyFunc(func(id *identity.XRHID) error {
+				called = true
+				return nil
+			})
+
+			req.Header.Set("x-rh-identity", getBase64(exampleHeader))
+			boilerWithPolicies(req, 403, "Forbidden: custom\n",
+				identity.RequireOrgID(),
+				identity.PolicyFunc(func(id *identity.XRHID) error { return errors.New("custom") }),
+				last)
+			Expect(called).To(BeFalse())
+		})
+
+		It("should describe built-in policies", func() {
+			Expect(fmt.Sprint(identity.RequireType("User", "System"))).To(Equal("RequireType(User, System)"))
+			Expect(fmt.Sprint(identity.RequireOrgID())).To(Equal("RequireOrgID"))
+		})
+	})
+})
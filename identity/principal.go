@@ -0,0 +1,94 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PrincipalKind is the normalized kind of the principal.
+type PrincipalKind string
+
+const (
+	PrincipalUser           PrincipalKind = "user"
+	PrincipalServiceAccount PrincipalKind = "service_account"
+	PrincipalSystem         PrincipalKind = "system"
+	PrincipalAssociate      PrincipalKind = "associate"
+	PrincipalX509           PrincipalKind = "x509"
+)
+
+// Principal is a normalized description of "who" is making the request, regardless of
+// the identity type.
+type Principal struct {
+	// Kind is the normalized kind of the principal.
+	Kind PrincipalKind
+
+	// ID is a stable unique identifier within the kind: user_id for users and service
+	// accounts, cn for systems, rhatUUID for associates and subject_dn normalized with
+	// NormalizeDN for X509 (the raw subject_dn when it cannot be parsed).
+	ID string
+
+	// Name is a display name: username, associate full name, system cn or the common
+	// name from subject_dn (the whole subject_dn when it has no common name).
+	Name string
+
+	// Email is the email address when available (users and associates).
+	Email string
+}
+
+// String returns the principal in the form of "kind:id".
+func (p Principal) String() string {
+	return string(p.Kind) + ":" + p.ID
+}
+
+var (
+	ErrUnknownIdentityType = errors.New("x-rh-identity header has an unknown type")
+	ErrMissingPrincipal    = errors.New("x-rh-identity header is missing principal details")
+)
+
+// Principal returns the normalized principal for all supported identity types. An error
+// wrapping ErrUnknownIdentityType is returned for unsupported types and an error wrapping
+// ErrMissingPrincipal when the type-specific field or its unique ID is missing.
+func (x *XRHID) Principal() (Principal, error) {
+	id := &x.Identity
+	var p Principal
+
+	switch id.IdentityType() {
+	case TypeUser:
+		if id.User != nil {
+			p = Principal{Kind: PrincipalUser, ID: id.User.UserID, Name: id.User.Username, Email: id.User.Email}
+		}
+	case TypeServiceAccount:
+		if id.ServiceAccount != nil {
+			p = Principal{Kind: PrincipalServiceAccount, ID: id.ServiceAccount.UserId, Name: id.ServiceAccount.Username}
+		}
+	case TypeSystem:
+		if id.System != nil {
+			p = Principal{Kind: PrincipalSystem, ID: id.System.CommonName, Name: id.System.CommonName}
+		}
+	case TypeAssociate:
+		if id.Associate != nil {
+			name := strings.TrimSpace(id.Associate.GivenName + " " + id.Associate.Surname)
+			p = Principal{Kind: PrincipalAssociate, ID: id.Associate.RHatUUID, Name: name, Email: id.Associate.Email}
+		}
+	case TypeX509:
+		if id.X509 != nil {
+			name := id.X509.SubjectDN
+			if dn, err := ParseDNName(name); err == nil && dn.CommonName != "" {
+				name = dn.CommonName
+			}
+			subject := id.X509.SubjectDN
+			if normalized, err := NormalizeDN(subject); err == nil {
+				subject = normalized
+			}
+			p = Principal{Kind: PrincipalX509, ID: subject, Name: name}
+		}
+	default:
+		return Principal{}, fmt.Errorf("%w: %q", ErrUnknownIdentityType, id.Type)
+	}
+
+	if p.ID == "" {
+		return Principal{}, fmt.Errorf("%w: %q", ErrMissingPrincipal, id.Type)
+	}
+	return p, nil
+}
@@ -0,0 +1,63 @@
+package identity_test
+
+import (
+	"errors"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Principal", func() {
+	It("should normalize all identity types", func() {
+		expected := []identity.Principal{
+			{Kind: identity.PrincipalUser, ID: "55555555", Name: "jdoe", Email: "jdoe@example.com"},
+			{Kind: identity.PrincipalSystem, ID: "4c2b0b5a-0a7e-4b6c-9a2f-3f8d2b1a6e10", Name: "4c2b0b5a-0a7e-4b6c-9a2f-3f8d2b1a6e10"},
+			{Kind: identity.PrincipalAssociate, ID: "01234567-89ab-cdef-0123-456789abcdef", Name: "John Doe", Email: "jdoe@redhat.com"},
+			{Kind: identity.PrincipalX509, ID: "CN=service.example.com,OU=insights,O=red hat", Name: "service.example.com"},
+			{Kind: identity.PrincipalServiceAccount, ID: "5d16465b-c0be-4cf6-a26f-084ebbc5e67d", Name: "service-account-b69eaf9e-e6a6-4f9e-805e-02987daddfbd"},
+		}
+
+		for i, b := range identitytest.All() {
+			id := b.Build()
+			p, err := id.Principal()
+			Expect(err).To(BeNil())
+			Expect(p).To(Equal(expected[i]))
+		}
+	})
+
+	It("should use the same ID for X509 subjects in different formats", func() {
+		openssl := identitytest.X509().SubjectDN("/O=Red Hat/OU=Insights/CN=service.example.com").Build()
+		rfc4514 := identitytest.X509().SubjectDN("CN=service.example.com, OU=Insights, O=Red Hat").Build()
+		a, err := openssl.Principal()
+		Expect(err).To(BeNil())
+		b, err := rfc4514.Principal()
+		Expect(err).To(BeNil())
+		Expect(a.ID).To(Equal(b.ID))
+
+		raw := identitytest.X509().SubjectDN("not a dn").Build()
+		p, err := raw.Principal()
+		Expect(err).To(BeNil())
+		Expect(p.ID).To(Equal("not a dn"))
+	})
+
+	It("should format as kind and ID", func() {
+		id := identitytest.User().Build()
+		p, _ := id.Principal()
+		Expect(p.String()).To(Equal("user:55555555"))
+	})
+
+	It("should fail for unknown types", func() {
+		id := identitytest.User().Type("Robot").Build()
+		_, err := id.Principal()
+		Expect(errors.Is(err, identity.ErrUnknownIdentityType)).To(BeTrue())
+	})
+
+	It("should fail when type-specific details are missing", func() {
+		id := identitytest.System().Modify(func(id *identity.XRHID) { id.Identity.System = nil }).Build()
+		_, err := id.Principal()
+		Expect(errors.Is(err, identity.ErrMissingPrincipal)).To(BeTrue())
+	})
+})
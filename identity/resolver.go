@@ -0,0 +1,249 @@
+package identity
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrResolveOrgID = errors.New("unable to resolve x-rh-identity org_id or account_number")
+
+// DefaultResolverTimeout is the timeout of HTTPResolver requests when no client is set.
+const DefaultResolverTimeout = 5 * time.Second
+
+var defaultResolverClient = &http.Client{Timeout: DefaultResolverTimeout}
+
+// OrgIDResolver translates EBS account numbers into org IDs and vice versa. Methods
+// return an empty string and nil error when no mapping exists, errors are reserved for
+// failures of the resolver itself. Implementations must be safe for concurrent use.
+type OrgIDResolver interface {
+	OrgID(ctx context.Context, accountNumber string) (string, error)
+	AccountNumber(ctx context.Context, orgID string) (string, error)
+}
+
+// resolve fills org_id of identities with account_number only and, when enabled,
+// account_number of identities with org_id only
+func (o DecodeOptions) resolve(ctx context.Context, id *XRHID) error {
+	if o.Resolver == nil {
+		return nil
+	}
+
+	var err error
+	i := &id.Identity
+	switch {
+	case i.OrgID == "" && i.AccountNumber != "":
+		i.OrgID, err = o.Resolver.OrgID(ctx, i.AccountNumber)
+		if err != nil {
+			return fmt.Errorf("%w: account_number %q: %w", ErrResolveOrgID, i.AccountNumber, err)
+		}
+	case o.ResolveAccountNumber && i.AccountNumber == "" && i.OrgID != "" && !i.IsAssociate() && !i.IsX509():
+		i.AccountNumber, err = o.Resolver.AccountNumber(ctx, i.OrgID)
+		if err != nil {
+			return fmt.Errorf("%w: org_id %q: %w", ErrResolveOrgID, i.OrgID, err)
+		}
+	}
+	return nil
+}
+
+// StaticResolver is an OrgIDResolver backed by a map of account numbers to org IDs,
+// it is useful for tests and development.
+type StaticResolver map[string]string
+
+// OrgID implements OrgIDResolver.
+func (s StaticResolver) OrgID(_ context.Context, accountNumber string) (string, error) {
+	return s[accountNumber], nil
+}
+
+// AccountNumber implements OrgIDResolver.
+func (s StaticResolver) AccountNumber(_ context.Context, orgID string) (string, error) {
+	for account, org := range s {
+		if org == orgID {
+			return account, nil
+		}
+	}
+	return "", nil
+}
+
+// HTTPResolver is an OrgIDResolver calling the tenant translation service API:
+//
+//	POST {URL}/internal/orgIds      ["account"] -> {"account": "org_id"}
+//	POST {URL}/internal/ebsNumbers  ["org_id"]  -> {"org_id": "account"}
+//
+// Wrap it with NewCachedResolver to avoid a request per decoded identity.
+type HTTPResolver struct {
+	// URL is the base URL of the service, e.g. http://tenant-translator:8000.
+	URL string
+
+	// Client is the HTTP client, a client with DefaultResolverTimeout is used when nil.
+	// A custom client should set a timeout because the resolver may be called with
+	// a context which is never cancelled.
+	Client *http.Client
+}
+
+// OrgID implements OrgIDResolver.
+func (h *HTTPResolver) OrgID(ctx context.Context, accountNumber string) (string, error) {
+	return h.translate(ctx, "/internal/orgIds", accountNumber)
+}
+
+// AccountNumber implements OrgIDResolver.
+func (h *HTTPResolver) AccountNumber(ctx context.Context, orgID string) (string, error) {
+	return h.translate(ctx, "/internal/ebsNumbers", orgID)
+}
+
+func (h *HTTPResolver) translate(ctx context.Context, path, value string) (string, error) {
+	body, err := json.Marshal([]string{value})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(h.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := h.Client
+	if client == nil {
+		client = defaultResolverClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return "", fmt.Errorf("%s returned %s", path, resp.Status)
+	}
+
+	var result map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("%s returned invalid JSON: %w", path, err)
+	}
+	return result[value], nil
+}
+
+// CachedResolver is a bounded LRU cache of an OrgIDResolver. Both found and missing
+// mappings are cached, errors are not. CachedResolver is safe for concurrent use.
+type CachedResolver struct {
+	resolver   OrgIDResolver
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+	mu         sync.Mutex
+	ll         *list.List
+	items      map[resolverKey]*list.Element
+}
+
+type resolverKey struct {
+	reverse bool
+	value   string
+}
+
+type resolverEntry struct {
+	key     resolverKey
+	result  string
+	expires time.Time
+}
+
+// NewCachedResolver returns a resolver caching up to maxEntries results (4096 when
+// zero) of the given resolver for the ttl, zero ttl means entries never expire.
+func NewCachedResolver(resolver OrgIDResolver, maxEntries int, ttl time.Duration) *CachedResolver {
+	if maxEntries <= 0 {
+		maxEntries = 4096
+	}
+	return &CachedResolver{
+		resolver:   resolver,
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		ll:         list.New(),
+		items:      make(map[resolverKey]*list.Element),
+	}
+}
+
+// OrgID implements OrgIDResolver.
+func (c *CachedResolver) OrgID(ctx context.Context, accountNumber string) (string, error) {
+	return c.lookup(ctx, resolverKey{value: accountNumber})
+}
+
+// AccountNumber implements OrgIDResolver.
+func (c *CachedResolver) AccountNumber(ctx context.Context, orgID string) (string, error) {
+	return c.lookup(ctx, resolverKey{reverse: true, value: orgID})
+}
+
+// Len returns the number of cached results.
+func (c *CachedResolver) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *CachedResolver) lookup(ctx context.Context, key resolverKey) (string, error) {
+	if result, ok := c.get(key); ok {
+		return result, nil
+	}
+
+	var result string
+	var err error
+	if key.reverse {
+		result, err = c.resolver.AccountNumber(ctx, key.value)
+	} else {
+		result, err = c.resolver.OrgID(ctx, key.value)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	c.add(key, result)
+	return result, nil
+}
+
+func (c *CachedResolver) get(key resolverKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+
+	e := el.Value.(*resolverEntry)
+	if c.ttl > 0 && c.now().After(e.expires) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return "", false
+	}
+
+	c.ll.MoveToFront(el)
+	return e.result, true
+}
+
+func (c *CachedResolver) add(key resolverKey, result string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.Remove(el)
+	}
+
+	e := &resolverEntry{key: key, result: result}
+	if c.ttl > 0 {
+		e.expires = c.now().Add(c.ttl)
+	}
+	c.items[key] = c.ll.PushFront(e)
+
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Remove(c.ll.Back()).(*resolverEntry)
+		delete(c.items, oldest.key)
+	}
+}
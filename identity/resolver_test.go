@@ -0,0 +1,119 @@
+package identity_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OrgIDResolver", func() {
+	resolver := identity.StaticResolver{"540155": "1979710"}
+	legacy := func() *identitytest.Builder {
+		return identitytest.User().OrgID("").AccountNumber("540155")
+	}
+
+	It("should resolve org_id during decode", func() {
+		opts := identity.DecodeOptions{Resolver: resolver}
+		id, err := opts.DecodeAndCheckIdentity(legacy().Header())
+		Expect(err).To(BeNil())
+		Expect(id.Identity.OrgID).To(Equal("1979710"))
+
+		_, err = identity.DecodeAndCheckIdentity(legacy().Header())
+		Expect(errors.Is(err, identity.ErrInvalidOrgIdIdentity)).To(BeTrue())
+
+		_, err = opts.DecodeAndCheckIdentity(legacy().AccountNumber("1").Header())
+		Expect(errors.Is(err, identity.ErrInvalidOrgIdIdentity)).To(BeTrue())
+	})
+
+	It("should resolve account_number when enabled", func() {
+		opts := identity.DecodeOptions{Resolver: resolver, ResolveAccountNumber: true}
+		id, err := opts.DecodeAndCheckIdentity(identitytest.User().AccountNumber("").Header())
+		Expect(err).To(BeNil())
+		Expect(id.Identity.AccountNumber).To(Equal("540155"))
+
+		id, err = opts.DecodeAndCheckIdentity(identitytest.Associate().OrgID("1979710").AccountNumber("").Header())
+		Expect(err).To(BeNil())
+		Expect(id.Identity.AccountNumber).To(BeEmpty())
+	})
+
+	It("should respond with 503 when the resolver fails", func() {
+		failing := identity.NewCachedResolver(&identity.HTTPResolver{URL: "http://127.0.0.1:1"}, 0, 0)
+		handler := identity.EnforceIdentityWithOptions(identity.WithDecodeOptions(identity.DecodeOptions{Resolver: failing}))(GetTestHandler(false))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, legacy().Request("GET", "/"))
+		Expect(rr.Code).To(Equal(503))
+		Expect(rr.Body.String()).To(HavePrefix(`Service Unavailable: unable to resolve x-rh-identity org_id or account_number: account_number "540155": `))
+		Expect(failing.Len()).To(Equal(0))
+	})
+
+	Context("With HTTPResolver", func() {
+		var (
+			server   *httptest.Server
+			requests atomic.Int32
+		)
+
+		BeforeEach(func() {
+			requests.Store(0)
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				var values []string
+				Expect(r.Method).To(Equal("POST"))
+				Expect(json.NewDecoder(r.Body).Decode(&values)).To(Succeed())
+
+				result := map[string]string{}
+				for _, v := range values {
+					switch {
+					case r.URL.Path == "/internal/orgIds" && v == "540155":
+						result[v] = "1979710"
+					case r.URL.Path == "/internal/ebsNumbers" && v == "1979710":
+						result[v] = "540155"
+					case r.URL.Path != "/internal/orgIds" && r.URL.Path != "/internal/ebsNumbers":
+						w.WriteHeader(404)
+						return
+					}
+				}
+				Expect(json.NewEncoder(w).Encode(result)).To(Succeed())
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should translate both directions", func() {
+			r := &identity.HTTPResolver{URL: server.URL + "/"}
+			Expect(r.OrgID(context.Background(), "540155")).To(Equal("1979710"))
+			Expect(r.AccountNumber(context.Background(), "1979710")).To(Equal("540155"))
+			Expect(r.OrgID(context.Background(), "1")).To(BeEmpty())
+		})
+
+		It("should report unexpected responses", func() {
+			r := &identity.HTTPResolver{URL: server.URL + "/api"}
+			_, err := r.OrgID(context.Background(), "540155")
+			Expect(err).To(MatchError("/internal/orgIds returned 404 Not Found"))
+		})
+
+		It("should cache found and missing mappings", func() {
+			r := identity.NewCachedResolver(&identity.HTTPResolver{URL: server.URL}, 2, 0)
+			for i := 0; i < 3; i++ {
+				Expect(r.OrgID(context.Background(), "540155")).To(Equal("1979710"))
+				Expect(r.OrgID(context.Background(), "1")).To(BeEmpty())
+			}
+			Expect(requests.Load()).To(Equal(int32(2)))
+
+			Expect(r.AccountNumber(context.Background(), "1979710")).To(Equal("540155"))
+			Expect(r.Len()).To(Equal(2))
+			Expect(requests.Load()).To(Equal(int32(3)))
+		})
+	})
+})
@@ -0,0 +1,115 @@
+package identity
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ErrorResponder writes the response for a request which was rejected by the identity
+// middleware. The status is the HTTP status code and err the reason of the rejection,
+// typically wrapping one of the exported sentinel errors.
+type ErrorResponder func(w http.ResponseWriter, r *http.Request, status int, err error)
+
+// errorCodes maps sentinel errors to stable error codes, first match wins
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrMissingIdentity, "missing_identity"},
+	{ErrDecodeIdentity, "decode_identity"},
+	{ErrIdentityLimit, "identity_limit"},
+	{ErrUnmarshalIdentity, "unmarshal_identity"},
+	{ErrInvalidOrgIdIdentity, "invalid_org_id"},
+	{ErrMissingIdentityType, "missing_identity_type"},
+	{ErrPolicyIdentityType, "disallowed_identity_type"},
+	{ErrPolicyAuthType, "disallowed_auth_type"},
+	{ErrPolicyMissingUser, "missing_user"},
+	{ErrPolicyNotOrgAdmin, "not_org_admin"},
+	{ErrNotEntitled, "not_entitled"},
+	{ErrMissingRoles, "missing_roles"},
+	{ErrUnknownIdentityType, "unknown_identity_type"},
+	{ErrMissingPrincipal, "missing_principal"},
+	{ErrX509NotAllowed, "x509_not_allowed"},
+	{ErrX509MissingCert, "missing_x509"},
+	{ErrPolicyMissingSystem, "missing_system"},
+	{ErrPolicyCertType, "disallowed_cert_type"},
+	{ErrPolicyCommonName, "invalid_cn"},
+	{ErrPolicyMissingClusterID, "missing_cluster_id"},
+	{ErrReauthenticationRequired, "reauthentication_required"},
+	{ErrCrossAccessDenied, "cross_access_denied"},
+	{ErrResolveOrgID, "resolve_org_id"},
+}
+
+// ErrorCode returns a stable error code for errors returned from decoding and policy
+// functions of this package. For unknown errors, "invalid_identity" is returned.
+func ErrorCode(err error) string {
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+	return "invalid_identity"
+}
+
+// PlainTextResponder writes a plain text error response in the form of
+// "Bad Request: missing x-rh-identity header". This is the default responder.
+func PlainTextResponder(w http.ResponseWriter, _ *http.Request, status int, err error) {
+	http.Error(w, http.StatusText(status)+": "+err.Error(), status)
+}
+
+// JSONError is an item of the JSON error response body.
+type JSONError struct {
+	Status string `json:"status"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// JSONErrorResponse is the JSON error response body used by the platform.
+type JSONErrorResponse struct {
+	Errors []JSONError `json:"errors"`
+}
+
+// JSONResponder writes an application/json error response in the platform format:
+//
+//	{"errors":[{"status":"400","code":"missing_identity","detail":"missing x-rh-identity header"}]}
+func JSONResponder(w http.ResponseWriter, _ *http.Request, status int, err error) {
+	writeJSON(w, "application/json", status, JSONErrorResponse{
+		Errors: []JSONError{{
+			Status: strconv.Itoa(status),
+			Code:   ErrorCode(err),
+			Detail: err.Error(),
+		}},
+	})
+}
+
+// Problem is the RFC 7807 problem details response body.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	Code   string `json:"code"`
+}
+
+// ProblemResponder writes an application/problem+json error response according to RFC 7807
+// with the stable error code as an extension member:
+//
+//	{"type":"about:blank","title":"Bad Request","status":400,"detail":"missing x-rh-identity header","code":"missing_identity"}
+func ProblemResponder(w http.ResponseWriter, _ *http.Request, status int, err error) {
+	writeJSON(w, "application/problem+json", status, Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+		Code:   ErrorCode(err),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, contentType string, status int, body any) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
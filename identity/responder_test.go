@@ -0,0 +1,66 @@
+package identity_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ErrorResponder", func() {
+	var (
+		req *http.Request
+		rr  *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		req, _ = http.NewRequest("GET", "/api/entitlements/v1/services/", nil)
+		rr = httptest.NewRecorder()
+	})
+
+	It("should map sentinel errors to stable codes", func() {
+		Expect(identity.ErrorCode(identity.ErrMissingIdentity)).To(Equal("missing_identity"))
+		Expect(identity.ErrorCode(fmt.Errorf("%w: trailing data", identity.ErrUnmarshalIdentity))).To(Equal("unmarshal_identity"))
+		Expect(identity.ErrorCode(fmt.Errorf("unknown"))).To(Equal("invalid_identity"))
+	})
+
+	It("should write JSON errors", func() {
+		handler := identity.EnforceIdentityWithOptions(identity.WithErrorResponder(identity.JSONResponder))
+		handler(GetTestHandler(false)).ServeHTTP(rr, req)
+
+		Expect(rr.Code).To(Equal(400))
+		Expect(rr.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(rr.Body.String()).To(MatchJSON(`{"errors":[{"status":"400","code":"missing_identity","detail":"missing x-rh-identity header"}]}`))
+	})
+
+	It("should write problem details", func() {
+		req.Header.Set("x-rh-identity", getBase64(serviceAccountIdentity))
+		handler := identity.EnforceIdentityWithOptions(
+			identity.WithPolicies(identity.RequireType("User")),
+			identity.WithErrorResponder(identity.ProblemResponder))
+		handler(GetTestHandler(false)).ServeHTTP(rr, req)
+
+		var problem identity.Problem
+		Expect(json.Unmarshal(rr.Body.Bytes(), &problem)).To(Succeed())
+		Expect(rr.Code).To(Equal(403))
+		Expect(rr.Header().Get("Content-Type")).To(Equal("application/problem+json"))
+		Expect(problem.Title).To(Equal("Forbidden"))
+		Expect(problem.Status).To(Equal(403))
+		Expect(problem.Code).To(Equal("disallowed_identity_type"))
+	})
+
+	It("should be used by middlewares later in the chain", func() {
+		req.Header.Set("x-rh-identity", getBase64(exampleHeader))
+		handler := identity.EnforceIdentityWithOptions(identity.WithErrorResponder(identity.JSONResponder))(
+			identity.EnforceEntitlements(nil, identity.EntitlementOptions{Bundles: []string{"openshift"}})(GetTestHandler(false)))
+		handler.ServeHTTP(rr, req)
+
+		Expect(rr.Code).To(Equal(403))
+		Expect(rr.Body.String()).To(MatchJSON(`{"errors":[{"status":"403","code":"not_entitled","detail":"x-rh-identity is not entitled to any of: openshift"}]}`))
+	})
+})
@@ -0,0 +1,130 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var ErrMissingRoles = errors.New("x-rh-identity associate is missing roles")
+
+// MissingRolesError is returned when an associate does not hold the required roles,
+// it wraps ErrMissingRoles.
+type MissingRolesError struct {
+	// Missing are the required roles the associate does not hold. When any of the
+	// roles is sufficient, all roles are listed.
+	Missing []string
+
+	// All is true when all roles were required.
+	All bool
+}
+
+func (e *MissingRolesError) Error() string {
+	mode := "any of"
+	if e.All {
+		mode = "all of"
+	}
+	return fmt.Sprintf("%s %s: %s", ErrMissingRoles.Error(), mode, strings.Join(e.Missing, ", "))
+}
+
+func (e *MissingRolesError) Unwrap() error {
+	return ErrMissingRoles
+}
+
+// RoleOptions configure associate role checks.
+type RoleOptions struct {
+	// Roles is the list of LDAP roles (groups) provided by Turnpike.
+	Roles []string
+
+	// RequireAll requires the associate to hold all roles, by default any of the
+	// roles is sufficient.
+	RequireAll bool
+
+	// X509 accepts X509 identities matching the allowlist instead of associates, for
+	// example internal services calling the endpoint through Turnpike with a
+	// certificate. X509 identities are rejected when nil.
+	X509 *X509Options
+}
+
+// HasRole returns true when the associate holds the given role.
+func (a *Associate) HasRole(role string) bool {
+	for _, r := range a.Role {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAssociateRoles returns nil when the identity is an associate holding the
+// configured roles, or an X509 identity matching the X509 allowlist when set. Other
+// identity types are rejected with an error wrapping ErrPolicyIdentityType, associates
+// without the roles with *MissingRolesError and X509 identities which are not allowed
+// with an error wrapping ErrX509NotAllowed. Associates are rejected when no roles are
+// configured.
+func CheckAssociateRoles(id *XRHID, opts RoleOptions) error {
+	if opts.X509 != nil && id.Identity.IsX509() {
+		return checkX509(id, *opts.X509)
+	}
+
+	if !id.Identity.IsAssociate() {
+		return fmt.Errorf("%w: %q", ErrPolicyIdentityType, id.Identity.Type)
+	}
+
+	if len(opts.Roles) == 0 {
+		return fmt.Errorf("%w: no roles configured", ErrMissingRoles)
+	}
+
+	associate := id.Identity.Associate
+	if associate == nil {
+		associate = &Associate{}
+	}
+
+	var missing []string
+	for _, role := range opts.Roles {
+		if associate.HasRole(role) {
+			if !opts.RequireAll {
+				return nil
+			}
+			continue
+		}
+		missing = append(missing, role)
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+	return &MissingRolesError{Missing: missing, All: opts.RequireAll}
+}
+
+// RequireAssociateRoles returns a policy performing CheckAssociateRoles. The function
+// panics when no roles are configured or the X509 allowlist is invalid, see RequireX509.
+func RequireAssociateRoles(opts RoleOptions) Policy {
+	if len(opts.Roles) == 0 {
+		panic("identity: RequireAssociateRoles needs at least one role")
+	}
+	mode := "any"
+	if opts.RequireAll {
+		mode = "all"
+	}
+	x509 := "none"
+	if opts.X509 != nil {
+		opts.X509.mustCompile()
+		x509 = opts.X509.String()
+	}
+	return namedPolicy{
+		name: fmt.Sprintf("RequireAssociateRoles(%s of %s; x509: %s)", mode, strings.Join(opts.Roles, ", "), x509),
+		fn: func(id *XRHID) error {
+			return CheckAssociateRoles(id, opts)
+		},
+	}
+}
+
+// EnforceAssociateRoles checks roles of the identity stored in the request context,
+// typically used for internal admin endpoints behind Turnpike. The middleware must be
+// placed after EnforceIdentityWithLogger or one of its variants. Requests without
+// required roles are aborted with HTTP code 403 and missing roles listed in the body.
+func EnforceAssociateRoles(logger ErrorFunc, opts RoleOptions) func(next http.Handler) http.Handler {
+	return enforcePolicies(logger, RequireAssociateRoles(opts))
+}
@@ -0,0 +1,76 @@
+package identity_test
+
+import (
+	"errors"
+	"net/http/httptest"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Associate roles", func() {
+	associate := identitytest.Associate().Roles("admin", "support")
+
+	check := func(b *identitytest.Builder, opts identity.RoleOptions) error {
+		id := b.Build()
+		return identity.CheckAssociateRoles(&id, opts)
+	}
+
+	It("should accept any of the roles", func() {
+		Expect(check(associate, identity.RoleOptions{Roles: []string{"ops", "support"}})).To(Succeed())
+	})
+
+	It("should list missing roles", func() {
+		err := check(associate, identity.RoleOptions{Roles: []string{"ops", "admin", "sre"}, RequireAll: true})
+		Expect(errors.Is(err, identity.ErrMissingRoles)).To(BeTrue())
+		Expect(err).To(MatchError("x-rh-identity associate is missing roles all of: ops, sre"))
+
+		var mre *identity.MissingRolesError
+		Expect(errors.As(err, &mre)).To(BeTrue())
+		Expect(mre.Missing).To(Equal([]string{"ops", "sre"}))
+	})
+
+	It("should reject associates when no roles are configured", func() {
+		err := check(associate, identity.RoleOptions{})
+		Expect(errors.Is(err, identity.ErrMissingRoles)).To(BeTrue())
+		Expect(func() { identity.EnforceAssociateRoles(nil, identity.RoleOptions{RequireAll: true}) }).To(Panic())
+	})
+
+	It("should reject other identity types", func() {
+		err := check(identitytest.User(), identity.RoleOptions{Roles: []string{"admin"}})
+		Expect(errors.Is(err, identity.ErrPolicyIdentityType)).To(BeTrue())
+	})
+
+	It("should accept X509 identities matching the allowlist", func() {
+		Expect(check(identitytest.X509(), identity.RoleOptions{Roles: []string{"admin"}})).ToNot(Succeed())
+
+		allowed := &identity.X509Options{Subjects: []string{"/O=Red Hat/OU=Insights/CN=service.example.com"}, Issuers: []string{"*"}}
+		Expect(check(identitytest.X509(), identity.RoleOptions{Roles: []string{"admin"}, X509: allowed})).To(Succeed())
+
+		other := &identity.X509Options{Subjects: []string{"/O=Red Hat/OU=Insights/CN=other.example.com"}, Issuers: []string{"*"}}
+		err := check(identitytest.X509(), identity.RoleOptions{Roles: []string{"admin"}, X509: other})
+		Expect(errors.Is(err, identity.ErrX509NotAllowed)).To(BeTrue())
+
+		err = check(identitytest.X509(), identity.RoleOptions{Roles: []string{"admin"}, X509: &identity.X509Options{}})
+		Expect(errors.Is(err, identity.ErrX509NotAllowed)).To(BeTrue())
+	})
+
+	It("should panic on malformed X509 patterns", func() {
+		Expect(func() {
+			identity.RequireAssociateRoles(identity.RoleOptions{Roles: []string{"admin"}, X509: &identity.X509Options{Subjects: []string{"*"}, Issuers: []string{"bogus"}}})
+		}).To(Panic())
+	})
+
+	It("should respond 403 with missing roles", func() {
+		rr := httptest.NewRecorder()
+		handler := identity.EnforceIdentityWithOptions(identity.WithErrorResponder(identity.JSONResponder))(
+			identity.EnforceAssociateRoles(nil, identity.RoleOptions{Roles: []string{"ops"}})(GetTestHandler(false)))
+		handler.ServeHTTP(rr, associate.Request("GET", "/api/app/v1/admin"))
+
+		Expect(rr.Code).To(Equal(403))
+		Expect(rr.Body.String()).To(MatchJSON(`{"errors":[{"status":"403","code":"missing_roles","detail":"x-rh-identity associate is missing roles any of: ops"}]}`))
+	})
+})
@@ -0,0 +1,294 @@
+package identity
+
+//go:generate sh -c "go run ../cmd/xrhid schema > xrhid.schema.json"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// jsonSchema is the subset of JSON Schema used to describe XRHID
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 jsonTypes              `json:"type,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+}
+
+// jsonTypes is the "type" keyword, encoded as a string when there is a single type
+type jsonTypes []string
+
+func (t jsonTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// JSONSchema returns the JSON Schema (draft 2020-12) of the decoded X-Rh-Identity
+// header. The schema is generated from the XRHID types, required fields mirror the
+// base policy performed by DecodeAndCheckIdentity. Unknown fields are allowed.
+//
+// The schema is also shipped in the xrhid.schema.json file for non-Go services.
+func JSONSchema() []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(xrhidSchema()); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+func xrhidSchema() *jsonSchema {
+	s := schemaOf(reflect.TypeOf(XRHID{}))
+	s.Schema = "https://json-schema.org/draft/2020-12/schema"
+	s.Title = "X-Rh-Identity"
+	s.Required = []string{"identity"}
+
+	nonEmpty, empty := 1, 0
+	s.Properties["identity"].AnyOf = []*jsonSchema{
+		{
+			Description: "identity with type and org_id",
+			Required:    []string{"type", "org_id"},
+			Properties: map[string]*jsonSchema{
+				"type":   {Type: jsonTypes{"string"}, MinLength: &nonEmpty},
+				"org_id": {Type: jsonTypes{"string"}, MinLength: &nonEmpty},
+			},
+		},
+		{
+			Description: "identity with type and internal.org_id",
+			Required:    []string{"type", "internal"},
+			Properties: map[string]*jsonSchema{
+				"type": {Type: jsonTypes{"string"}, MinLength: &nonEmpty},
+				"internal": {
+					Required:   []string{"org_id"},
+					Properties: map[string]*jsonSchema{"org_id": {Type: jsonTypes{"string"}, MinLength: &nonEmpty}},
+				},
+			},
+		},
+		{
+			Description: "Associate or X509 identity without account_number",
+			Required:    []string{"type"},
+			Properties: map[string]*jsonSchema{
+				"type":           {Type: jsonTypes{"string"}, Enum: []string{string(TypeAssociate), string(TypeX509)}},
+				"account_number": {Type: jsonTypes{"string"}, MaxLength: &empty},
+			},
+		},
+	}
+	return s
+}
+
+// schemaOf returns schema of the Go type, struct fields are described by their JSON tags.
+// Like the decoder, pointer, slice and map values also accept null.
+func schemaOf(t reflect.Type) *jsonSchema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schemaOf(t.Elem())
+		if len(s.Type) > 0 {
+			s.Type = append(s.Type, "null")
+		}
+		return s
+	case reflect.String:
+		return &jsonSchema{Type: jsonTypes{"string"}}
+	case reflect.Bool:
+		return &jsonSchema{Type: jsonTypes{"boolean"}}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: jsonTypes{"number"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: jsonTypes{"integer"}}
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: jsonTypes{"array", "null"}, Items: schemaOf(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: jsonTypes{"object", "null"}, AdditionalProperties: schemaOf(t.Elem())}
+	case reflect.Struct:
+		s := &jsonSchema{Type: jsonTypes{"object"}, Properties: map[string]*jsonSchema{}}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			s.Properties[name] = schemaOf(f.Type)
+		}
+		return s
+	default:
+		return &jsonSchema{}
+	}
+}
+
+// SchemaError is a single JSON Schema violation.
+type SchemaError struct {
+	// Path is the dot-separated path of the offending value, empty for the root.
+	Path string
+
+	// Message describes the violation.
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// SchemaErrors is a list of all JSON Schema violations.
+type SchemaErrors []SchemaError
+
+func (e SchemaErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, se := range e {
+		msgs[i] = se.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateSchema checks the decoded X-Rh-Identity header (JSON) against JSONSchema and
+// returns SchemaErrors with all violations, nil when the document is valid, or an error
+// wrapping ErrUnmarshalIdentity when it is not valid JSON.
+func ValidateSchema(raw []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("%w: %s", ErrUnmarshalIdentity, err)
+	}
+
+	errs := validateSchema(xrhidSchema(), doc, "")
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateSchema(s *jsonSchema, v any, path string) SchemaErrors {
+	var errs SchemaErrors
+	fail := func(format string, args ...any) {
+		errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !schemaTypes(s.Type, v) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), jsonType(v))
+		return errs
+	}
+
+	switch v := v.(type) {
+	case string:
+		if len(s.Enum) > 0 {
+			found := false
+			for _, e := range s.Enum {
+				found = found || e == v
+			}
+			if !found {
+				fail("must be one of %s, got %q", strings.Join(s.Enum, ", "), v)
+			}
+		}
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			fail("must not be shorter than %d", *s.MinLength)
+		}
+		if s.MaxLength != nil && len(v) > *s.MaxLength {
+			fail("must not be longer than %d", *s.MaxLength)
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				errs = append(errs, validateSchema(s.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail("missing required field %q", name)
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + "." + k
+			if path == "" {
+				child = k
+			}
+			if ps, ok := s.Properties[k]; ok {
+				errs = append(errs, validateSchema(ps, v[k], child)...)
+			} else if s.AdditionalProperties != nil {
+				errs = append(errs, validateSchema(s.AdditionalProperties, v[k], child)...)
+			}
+		}
+	}
+
+	if len(s.AnyOf) > 0 {
+		descriptions := make([]string, 0, len(s.AnyOf))
+		matched := false
+		for _, alt := range s.AnyOf {
+			if len(validateSchema(alt, v, path)) == 0 {
+				matched = true
+				break
+			}
+			descriptions = append(descriptions, alt.Description)
+		}
+		if !matched {
+			fail("does not match any of: %s", strings.Join(descriptions, ", "))
+		}
+	}
+	return errs
+}
+
+func schemaTypes(types jsonTypes, v any) bool {
+	for _, t := range types {
+		if schemaType(t, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaType(t string, v any) bool {
+	if t == "integer" {
+		if n, ok := v.(json.Number); ok {
+			_, err := n.Int64()
+			return err == nil
+		}
+		return false
+	}
+	return jsonType(v) == t
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
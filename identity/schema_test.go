@@ -0,0 +1,84 @@
+package identity_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSON Schema", func() {
+	It("should match the shipped schema file", func() {
+		shipped, err := os.ReadFile("xrhid.schema.json")
+		Expect(err).To(BeNil())
+		Expect(string(identity.JSONSchema())).To(Equal(string(shipped)), "run go generate to update xrhid.schema.json")
+		Expect(json.Valid(shipped)).To(BeTrue())
+	})
+
+	It("should accept all fixtures", func() {
+		for _, b := range identitytest.All() {
+			Expect(identity.ValidateSchema(b.JSON())).To(Succeed())
+		}
+		Expect(identity.ValidateSchema([]byte(validJson[0]))).To(Succeed())
+	})
+
+	It("should accept null where the decoder does", func() {
+		doc := `{"identity": {"type": "User", "org_id": "1", "user": null, "associate": {"Role": null}}, "entitlements": null}`
+		_, err := identity.DecodeAndCheckIdentity(getBase64(doc))
+		Expect(err).To(BeNil())
+		Expect(identity.ValidateSchema([]byte(doc))).To(Succeed())
+
+		err = identity.ValidateSchema([]byte(`{"identity": {"type": "User", "org_id": "1", "internal": null}}`))
+		Expect(err).To(MatchError("identity.internal: expected object, got null"))
+
+		err = identity.ValidateSchema([]byte(`{"identity": {"type": "User", "org_id": "1", "user": 1}}`))
+		Expect(err).To(MatchError("identity.user: expected object or null, got number"))
+	})
+
+	It("should mirror the base policy", func() {
+		for _, doc := range []string{
+			`{"identity": {"type": "User", "org_id": "1"}}`,
+			`{"identity": {"type": "User", "internal": {"org_id": "1"}}}`,
+			`{"identity": {"type": "Associate"}}`,
+			`{"identity": {"type": "X509", "account_number": ""}}`,
+		} {
+			_, err := identity.DecodeAndCheckIdentity(getBase64(doc))
+			Expect(err).To(BeNil(), doc)
+			Expect(identity.ValidateSchema([]byte(doc))).To(Succeed(), doc)
+		}
+
+		for _, doc := range []string{
+			`{"identity": {"type": "User"}}`,
+			`{"identity": {"type": "", "org_id": "1"}}`,
+			`{"identity": {"type": "Associate", "account_number": "1"}}`,
+		} {
+			_, err := identity.DecodeAndCheckIdentity(getBase64(doc))
+			Expect(err).ToNot(BeNil(), doc)
+			Expect(identity.ValidateSchema([]byte(doc))).ToNot(Succeed(), doc)
+		}
+	})
+
+	It("should report all violations", func() {
+		err := identity.ValidateSchema([]byte(`{"identity": {"type": 1, "internal": {"auth_time": "now"}, "user": {"is_org_admin": "yes"}}, "entitlements": {"insights": {"is_trial": 0}}}`))
+
+		var errs identity.SchemaErrors
+		Expect(errors.As(err, &errs)).To(BeTrue())
+		Expect(errs).To(Equal(identity.SchemaErrors{
+			{Path: "entitlements.insights.is_trial", Message: "expected boolean, got number"},
+			{Path: "identity.internal.auth_time", Message: "expected number, got string"},
+			{Path: "identity.type", Message: "expected string, got number"},
+			{Path: "identity.user.is_org_admin", Message: "expected boolean, got string"},
+			{Path: "identity", Message: "does not match any of: identity with type and org_id, identity with type and internal.org_id, Associate or X509 identity without account_number"},
+		}))
+	})
+
+	It("should reject invalid JSON", func() {
+		err := identity.ValidateSchema([]byte(`{`))
+		Expect(errors.Is(err, identity.ErrUnmarshalIdentity)).To(BeTrue())
+	})
+})
@@ -0,0 +1,121 @@
+package identity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+var errTrailingData = errors.New("trailing data after top-level value")
+
+// unmarshalStrict decodes the identity rejecting unknown fields, duplicate keys and
+// trailing data. Values of wrong types are rejected in both modes.
+func unmarshalStrict(data []byte, id *XRHID) error {
+	if err := checkDuplicateKeys(data, reflect.TypeOf(XRHID{})); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode((*xrhidFields)(id)); err != nil {
+		return err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return errTrailingData
+	}
+
+	// unknown fields are collected like in the default mode to report their full path
+	id.Extra = collectExtra(data)
+	if unknown := id.unknownFields(); len(unknown) > 0 {
+		return fmt.Errorf("unknown field %q", unknown[0])
+	}
+	return nil
+}
+
+// checkDuplicateKeys streams JSON tokens and returns an error for the first object
+// with a repeated key. Like encoding/json, keys of struct fields are compared
+// case-insensitively, keys of maps and unknown fields exactly. Syntax errors are
+// ignored here and reported by unmarshalling.
+func checkDuplicateKeys(data []byte, t reflect.Type) error {
+	type frame struct {
+		t       reflect.Type // container type, nil when unknown
+		keys    map[string]struct{}
+		keyNext bool
+		value   reflect.Type // type of the value after the last key
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var stack []frame
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		if tok == json.Delim('}') || tok == json.Delim(']') {
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 && stack[len(stack)-1].keys != nil {
+				stack[len(stack)-1].keyNext = true
+			}
+			continue
+		}
+
+		var top *frame
+		if len(stack) > 0 {
+			top = &stack[len(stack)-1]
+		}
+
+		if top != nil && top.keys != nil && top.keyNext {
+			key, _ := tok.(string)
+			name, value := key, reflect.Type(nil)
+			switch {
+			case top.t == nil:
+			case top.t.Kind() == reflect.Map:
+				value = top.t.Elem()
+			case top.t.Kind() == reflect.Struct:
+				if f, ok := jsonFields(top.t)[strings.ToLower(key)]; ok {
+					name, value = f.name, f.typ
+				}
+			}
+			if _, ok := top.keys[name]; ok {
+				return fmt.Errorf("duplicate key %q", key)
+			}
+			top.keys[name] = struct{}{}
+			top.keyNext = false
+			top.value = value
+			continue
+		}
+
+		// type of the value starting with this token
+		vt := t
+		if top != nil {
+			vt = top.value
+			if top.keys == nil && top.t != nil {
+				vt = top.t.Elem()
+			}
+		}
+		for vt != nil && vt.Kind() == reflect.Pointer {
+			vt = vt.Elem()
+		}
+		if vt != nil && vt.Kind() != reflect.Struct && vt.Kind() != reflect.Map && vt.Kind() != reflect.Slice && vt.Kind() != reflect.Array {
+			vt = nil
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, frame{t: vt, keys: make(map[string]struct{}), keyNext: true})
+		case json.Delim('['):
+			stack = append(stack, frame{t: vt})
+		default:
+			if top != nil && top.keys != nil {
+				top.keyNext = true
+			}
+		}
+	}
+}
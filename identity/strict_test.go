@@ -0,0 +1,78 @@
+package identity_test
+
+import (
+	"errors"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Strict decoding", func() {
+	strict := identity.DecodeOptions{Strict: true}
+
+	It("should accept all valid fixtures", func() {
+		for _, json := range validJson {
+			_, err := strict.DecodeAndCheckIdentity(getBase64(json))
+			Expect(err).To(BeNil())
+		}
+	})
+
+	It("should reject unknown fields", func() {
+		json := `{"identity": {"type": "User", "org_id": "1", "internal": {"org_id": "1", "unknown": 1}}}`
+		_, err := identity.DecodeAndCheckIdentity(getBase64(json))
+		Expect(err).To(BeNil())
+
+		_, err = strict.DecodeAndCheckIdentity(getBase64(json))
+		Expect(errors.Is(err, identity.ErrUnmarshalIdentity)).To(BeTrue())
+		Expect(err).To(MatchError(`x-rh-identity header does not contain valid JSON: unknown field "identity.internal.unknown"`))
+	})
+
+	It("should reject duplicate keys", func() {
+		json := `{"identity": {"type": "User", "org_id": "1", "org_id": "2"}}`
+		id, err := identity.DecodeAndCheckIdentity(getBase64(json))
+		Expect(err).To(BeNil())
+		Expect(id.Identity.OrgID).To(Equal("2"))
+
+		_, err = strict.DecodeAndCheckIdentity(getBase64(json))
+		Expect(err).To(MatchError(`x-rh-identity header does not contain valid JSON: duplicate key "org_id"`))
+	})
+
+	It("should reject duplicate keys differing in case", func() {
+		json := `{"identity": {"type": "User", "org_id": "1", "ORG_ID": "2"}}`
+		id, err := identity.DecodeAndCheckIdentity(getBase64(json))
+		Expect(err).To(BeNil())
+		Expect(id.Identity.OrgID).To(Equal("2"))
+
+		_, err = strict.DecodeAndCheckIdentity(getBase64(json))
+		Expect(err).To(MatchError(`x-rh-identity header does not contain valid JSON: duplicate key "ORG_ID"`))
+
+		json = `{"identity": {"type": "User", "org_id": "1", "internal": {"org_id": "1", "Org_Id": "2"}}}`
+		_, err = strict.DecodeAndCheckIdentity(getBase64(json))
+		Expect(err).To(MatchError(`x-rh-identity header does not contain valid JSON: duplicate key "Org_Id"`))
+	})
+
+	It("should compare entitlement bundles exactly", func() {
+		json := `{"identity": {"type": "User", "org_id": "1"}, "entitlements": {"insights": {"is_entitled": true}, "Insights": {"is_entitled": false}}}`
+		id, err := strict.DecodeAndCheckIdentity(getBase64(json))
+		Expect(err).To(BeNil())
+		Expect(id.Entitlements).To(HaveLen(2))
+	})
+
+	It("should allow equal keys in different objects", func() {
+		json := `{"identity": {"type": "User", "org_id": "1", "internal": {"org_id": "1"}}}`
+		_, err := strict.DecodeAndCheckIdentity(getBase64(json))
+		Expect(err).To(BeNil())
+	})
+
+	It("should reject trailing data", func() {
+		_, err := strict.DecodeAndCheckIdentity(getBase64(exampleHeader + `{}`))
+		Expect(err).To(MatchError("x-rh-identity header does not contain valid JSON: trailing data after top-level value"))
+	})
+
+	It("should reject wrong types", func() {
+		_, err := strict.DecodeAndCheckIdentity(getBase64(`{"identity": {"type": "User", "org_id": 1}}`))
+		Expect(errors.Is(err, identity.ErrUnmarshalIdentity)).To(BeTrue())
+	})
+})
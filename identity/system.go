@@ -0,0 +1,106 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CertType is the "cert_type" field of the system identity.
+type CertType string
+
+// Known certificate types.
+const (
+	CertTypeSystem     CertType = "system"
+	CertTypeSatellite  CertType = "satellite"
+	CertTypeHypervisor CertType = "hypervisor"
+)
+
+var (
+	ErrPolicyMissingSystem    = errors.New("x-rh-identity header is missing system details")
+	ErrPolicyCertType         = errors.New("x-rh-identity system has a disallowed cert_type")
+	ErrPolicyCommonName       = errors.New("x-rh-identity system has an invalid cn")
+	ErrPolicyMissingClusterID = errors.New("x-rh-identity system is missing cluster_id")
+)
+
+// UUIDPattern matches UUID strings in the canonical textual form, it can be used with
+// RequireCommonName.
+var UUIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// CertificateType returns the "cert_type" field as CertType.
+func (s *System) CertificateType() CertType {
+	return CertType(s.CertType)
+}
+
+// system returns system details of a System identity or an error
+func system(id *XRHID) (*System, error) {
+	if !id.Identity.IsSystem() {
+		return nil, fmt.Errorf("%w: %q", ErrPolicyIdentityType, id.Identity.Type)
+	}
+	if id.Identity.System == nil {
+		return nil, ErrPolicyMissingSystem
+	}
+	return id.Identity.System, nil
+}
+
+// RequireCertType returns a policy that accepts only system identities with one of the
+// given certificate types. Other identity types are rejected.
+func RequireCertType(certTypes ...CertType) Policy {
+	names := make([]string, len(certTypes))
+	for i, t := range certTypes {
+		names[i] = string(t)
+	}
+
+	return namedPolicy{
+		name: fmt.Sprintf("RequireCertType(%s)", strings.Join(names, ", ")),
+		fn: func(id *XRHID) error {
+			s, err := system(id)
+			if err != nil {
+				return err
+			}
+			for _, t := range certTypes {
+				if s.CertificateType() == t {
+					return nil
+				}
+			}
+			return fmt.Errorf("%w: %q", ErrPolicyCertType, s.CertType)
+		},
+	}
+}
+
+// RequireCommonName returns a policy that accepts only system identities with cn
+// matching the pattern, for example UUIDPattern. Other identity types are rejected.
+func RequireCommonName(pattern *regexp.Regexp) Policy {
+	return namedPolicy{
+		name: fmt.Sprintf("RequireCommonName(%s)", pattern),
+		fn: func(id *XRHID) error {
+			s, err := system(id)
+			if err != nil {
+				return err
+			}
+			if !pattern.MatchString(s.CommonName) {
+				return fmt.Errorf("%w: %q", ErrPolicyCommonName, s.CommonName)
+			}
+			return nil
+		},
+	}
+}
+
+// RequireClusterID returns a policy that accepts only system identities with cluster_id.
+// Other identity types are rejected.
+func RequireClusterID() Policy {
+	return namedPolicy{
+		name: "RequireClusterID",
+		fn: func(id *XRHID) error {
+			s, err := system(id)
+			if err != nil {
+				return err
+			}
+			if s.ClusterId == "" {
+				return ErrPolicyMissingClusterID
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package identity_test
+
+import (
+	"errors"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("System policies", func() {
+	check := func(b *identitytest.Builder, p identity.Policy) error {
+		id := b.Build()
+		return p.Check(&id)
+	}
+
+	It("should check cert types", func() {
+		p := identity.RequireCertType(identity.CertTypeSatellite)
+		Expect(check(identitytest.System().CertType("satellite"), p)).To(Succeed())
+
+		err := check(identitytest.System(), p)
+		Expect(errors.Is(err, identity.ErrPolicyCertType)).To(BeTrue())
+		Expect(identity.ErrorCode(err)).To(Equal("disallowed_cert_type"))
+	})
+
+	It("should check cn format", func() {
+		p := identity.RequireCommonName(identity.UUIDPattern)
+		Expect(check(identitytest.System(), p)).To(Succeed())
+
+		err := check(identitytest.System().CommonName("my-host"), p)
+		Expect(errors.Is(err, identity.ErrPolicyCommonName)).To(BeTrue())
+	})
+
+	It("should require cluster_id", func() {
+		p := identity.RequireClusterID()
+		Expect(check(identitytest.System().ClusterID("c1"), p)).To(Succeed())
+
+		err := check(identitytest.System(), p)
+		Expect(errors.Is(err, identity.ErrPolicyMissingClusterID)).To(BeTrue())
+	})
+
+	It("should reject other identity types", func() {
+		err := check(identitytest.User(), identity.RequireClusterID())
+		Expect(errors.Is(err, identity.ErrPolicyIdentityType)).To(BeTrue())
+
+		err = check(identitytest.System().Modify(func(id *identity.XRHID) { id.Identity.System = nil }), identity.RequireClusterID())
+		Expect(errors.Is(err, identity.ErrPolicyMissingSystem)).To(BeTrue())
+	})
+
+	It("should round trip owner_id", func() {
+		id, err := identity.DecodeAndCheckIdentity(identitytest.System().OwnerID("o1").Header())
+		Expect(err).To(BeNil())
+		Expect(id.Identity.System.OwnerID).To(Equal("o1"))
+		Expect(id.Extra).To(BeEmpty())
+	})
+})
@@ -0,0 +1,104 @@
+package identity
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/request_id"
+)
+
+// Transport is an http.RoundTripper which propagates X-Rh-Identity and X-Request-Id
+// headers from the outbound request context to downstream services.
+//
+// The raw identity stored via WithRawIdentity is preferred, when only parsed identity
+// is present it is encoded with EncodeIdentity. Request ID is read with
+// request_id.GetReqID. Headers which are already set on the request are never
+// overwritten.
+type Transport struct {
+	// Base is the underlying RoundTripper, http.DefaultTransport is used when nil.
+	Base http.RoundTripper
+
+	// AllowedHosts limits propagation to the listed host names, when empty headers
+	// are not propagated at all. Entries starting with "*." match any subdomain.
+	AllowedHosts []string
+
+	// AllowAllHosts propagates headers to every host regardless of AllowedHosts. It
+	// must only be used when all outbound requests go to trusted internal services.
+	AllowAllHosts bool
+
+	// RequestIDHeader is the name of the request ID header, X-Request-Id when empty.
+	RequestIDHeader string
+}
+
+// NewClient returns a HTTP client using Transport with the default transport and
+// the given host allowlist. Headers are only sent to the listed hosts, so a client
+// without any hosts never propagates them.
+func NewClient(allowedHosts ...string) *http.Client {
+	return &http.Client{
+		Transport: &Transport{AllowedHosts: allowedHosts},
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if !t.allowed(req.URL.Hostname()) {
+		return base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	reqIDHeader := t.RequestIDHeader
+	if reqIDHeader == "" {
+		reqIDHeader = "X-Request-Id"
+	}
+
+	rawID := ""
+	if req.Header.Get("X-Rh-Identity") == "" {
+		rawID = GetRawIdentity(ctx)
+		if rawID == "" {
+			rawID = EncodeIdentity(ctx)
+		}
+	}
+
+	reqID := ""
+	if req.Header.Get(reqIDHeader) == "" {
+		reqID = request_id.GetReqID(ctx)
+	}
+
+	if rawID == "" && reqID == "" {
+		return base.RoundTrip(req)
+	}
+
+	// RoundTripper must not modify the original request
+	nr := req.Clone(ctx)
+	if rawID != "" {
+		nr.Header.Set("X-Rh-Identity", rawID)
+	}
+	if reqID != "" {
+		nr.Header.Set(reqIDHeader, reqID)
+	}
+	return base.RoundTrip(nr)
+}
+
+func (t *Transport) allowed(host string) bool {
+	if t.AllowAllHosts {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	for _, allowed := range t.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(host, allowed[1:]) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
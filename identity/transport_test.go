@@ -0,0 +1,94 @@
+package identity_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/request_id"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transport", func() {
+	var (
+		server   *httptest.Server
+		received http.Header
+	)
+
+	BeforeEach(func() {
+		received = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// get performs an outbound request from within a handler with identity and request ID in context
+	get := func(client *http.Client, ctxFn func(ctx context.Context) context.Context) {
+		handler := request_id.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			out, err := http.NewRequestWithContext(ctxFn(r.Context()), "GET", server.URL, nil)
+			Expect(err).To(BeNil())
+			resp, err := client.Do(out)
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+			Expect(out.Header.Get("X-Rh-Identity")).To(BeEmpty())
+		}))
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-Id", "test-request")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	It("should propagate raw identity and request ID", func() {
+		get(identity.NewClient("127.0.0.1"), func(ctx context.Context) context.Context {
+			return identity.WithRawIdentity(ctx, "raw")
+		})
+		Expect(received.Get("X-Rh-Identity")).To(Equal("raw"))
+		Expect(received.Get("X-Request-Id")).To(Equal("test-request"))
+	})
+
+	It("should encode parsed identity when raw identity is missing", func() {
+		get(identity.NewClient("127.0.0.1"), func(ctx context.Context) context.Context {
+			return identity.WithIdentity(ctx, identity.XRHID{Identity: identity.Identity{OrgID: "1979710", Type: "User"}})
+		})
+		id, err := identity.DecodeAndCheckIdentity(received.Get("X-Rh-Identity"))
+		Expect(err).To(BeNil())
+		Expect(id.Identity.OrgID).To(Equal("1979710"))
+	})
+
+	It("should not propagate to hosts which are not allowed", func() {
+		get(identity.NewClient("*.svc.cluster.local", "example.com"), func(ctx context.Context) context.Context {
+			return identity.WithRawIdentity(ctx, "raw")
+		})
+		Expect(received.Get("X-Rh-Identity")).To(BeEmpty())
+		Expect(received.Get("X-Request-Id")).To(BeEmpty())
+	})
+
+	It("should not propagate without allowed hosts", func() {
+		get(identity.NewClient(), func(ctx context.Context) context.Context {
+			return identity.WithRawIdentity(ctx, "raw")
+		})
+		Expect(received.Get("X-Rh-Identity")).To(BeEmpty())
+		Expect(received.Get("X-Request-Id")).To(BeEmpty())
+	})
+
+	It("should propagate to all hosts when explicitly allowed", func() {
+		client := &http.Client{Transport: &identity.Transport{AllowAllHosts: true}}
+		get(client, func(ctx context.Context) context.Context {
+			return identity.WithRawIdentity(ctx, "raw")
+		})
+		Expect(received.Get("X-Rh-Identity")).To(Equal("raw"))
+	})
+
+	It("should propagate to allowed hosts", func() {
+		get(identity.NewClient("127.0.0.1"), func(ctx context.Context) context.Context {
+			return identity.WithRawIdentity(ctx, "raw")
+		})
+		Expect(received.Get("X-Rh-Identity")).To(Equal("raw"))
+	})
+})
@@ -0,0 +1,125 @@
+package identity
+
+import "fmt"
+
+// IdentityType is the "type" field of the identity.
+type IdentityType string
+
+// Known identity types.
+const (
+	TypeUser           IdentityType = "User"
+	TypeSystem         IdentityType = "System"
+	TypeAssociate      IdentityType = "Associate"
+	TypeX509           IdentityType = "X509"
+	TypeServiceAccount IdentityType = "ServiceAccount"
+)
+
+// IdentityTypes is the list of all known identity types.
+var IdentityTypes = []IdentityType{TypeUser, TypeSystem, TypeAssociate, TypeX509, TypeServiceAccount}
+
+// Known returns true for identity types which are supported by this package.
+func (t IdentityType) Known() bool {
+	for _, known := range IdentityTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthType is the "auth_type" field of the identity.
+type AuthType string
+
+// Known auth types.
+const (
+	AuthTypeBasic AuthType = "basic-auth"
+	AuthTypeCert  AuthType = "cert-auth"
+	AuthTypeJWT   AuthType = "jwt-auth"
+	AuthTypeUHC   AuthType = "uhc-auth"
+	AuthTypeSAML  AuthType = "saml-auth"
+	AuthTypeX509  AuthType = "X509"
+)
+
+// AuthTypes is the list of all known auth types.
+var AuthTypes = []AuthType{AuthTypeBasic, AuthTypeCert, AuthTypeJWT, AuthTypeUHC, AuthTypeSAML, AuthTypeX509}
+
+// Known returns true for auth types which are known to be used by the platform.
+func (t AuthType) Known() bool {
+	for _, known := range AuthTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IdentityType returns the "type" field as IdentityType.
+func (i *Identity) IdentityType() IdentityType {
+	return IdentityType(i.Type)
+}
+
+// AuthenticationType returns the "auth_type" field as AuthType.
+func (i *Identity) AuthenticationType() AuthType {
+	return AuthType(i.AuthType)
+}
+
+// IsUser returns true for identities of the User type.
+func (i *Identity) IsUser() bool {
+	return i.Type == string(TypeUser)
+}
+
+// IsSystem returns true for identities of the System type.
+func (i *Identity) IsSystem() bool {
+	return i.Type == string(TypeSystem)
+}
+
+// IsAssociate returns true for identities of the Associate type.
+func (i *Identity) IsAssociate() bool {
+	return i.Type == string(TypeAssociate)
+}
+
+// IsX509 returns true for identities of the X509 type.
+func (i *Identity) IsX509() bool {
+	return i.Type == string(TypeX509)
+}
+
+// IsServiceAccount returns true for identities of the ServiceAccount type.
+func (i *Identity) IsServiceAccount() bool {
+	return i.Type == string(TypeServiceAccount)
+}
+
+// ValidateType checks that the identity type is known and that the type-specific
+// field (e.g. "user" for the User type) is present. It returns an error wrapping
+// ErrUnknownIdentityType or ErrMissingPrincipal respectively.
+func (i *Identity) ValidateType() error {
+	var present bool
+	switch i.IdentityType() {
+	case TypeUser:
+		present = i.User != nil
+	case TypeSystem:
+		present = i.System != nil
+	case TypeAssociate:
+		present = i.Associate != nil
+	case TypeX509:
+		present = i.X509 != nil
+	case TypeServiceAccount:
+		present = i.ServiceAccount != nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownIdentityType, i.Type)
+	}
+
+	if !present {
+		return fmt.Errorf("%w: %q", ErrMissingPrincipal, i.Type)
+	}
+	return nil
+}
+
+// RequireTypeDetails returns a policy performing Identity.ValidateType.
+func RequireTypeDetails() Policy {
+	return namedPolicy{
+		name: "RequireTypeDetails",
+		fn: func(id *XRHID) error {
+			return id.Identity.ValidateType()
+		},
+	}
+}
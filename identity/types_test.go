@@ -0,0 +1,46 @@
+package identity_test
+
+import (
+	"errors"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Types", func() {
+	It("should recognize known types", func() {
+		Expect(identity.TypeServiceAccount.Known()).To(BeTrue())
+		Expect(identity.IdentityType("user").Known()).To(BeFalse())
+		Expect(identity.AuthTypeUHC.Known()).To(BeTrue())
+		Expect(identity.AuthType("token").Known()).To(BeFalse())
+	})
+
+	It("should provide type predicates", func() {
+		id := identitytest.ServiceAccount().Build()
+		Expect(id.Identity.IsServiceAccount()).To(BeTrue())
+		Expect(id.Identity.IsUser()).To(BeFalse())
+		Expect(id.Identity.AuthenticationType()).To(Equal(identity.AuthTypeJWT))
+	})
+
+	It("should validate type details of all fixtures", func() {
+		for _, b := range identitytest.All() {
+			id := b.Build()
+			Expect(id.Identity.ValidateType()).To(Succeed())
+		}
+	})
+
+	It("should reject missing type details", func() {
+		id := identitytest.System().Type(identity.TypeUser).Modify(func(id *identity.XRHID) { id.Identity.User = nil }).Build()
+		err := identity.RequireTypeDetails().Check(&id)
+		Expect(errors.Is(err, identity.ErrMissingPrincipal)).To(BeTrue())
+	})
+
+	It("should reject unknown types", func() {
+		id := identitytest.User().Type("Robot").Build()
+		err := id.Identity.ValidateType()
+		Expect(errors.Is(err, identity.ErrUnknownIdentityType)).To(BeTrue())
+	})
+})
@@ -0,0 +1,103 @@
+/*
+Package fixtures holds the canned identities shared by the identitytest builders, the
+development identity injector and the xrhid tool, so the known-good values are only
+defined once. It must not depend on test-only packages.
+*/
+package fixtures
+
+import (
+	"fmt"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+)
+
+// Default values used by all identities.
+const (
+	DefaultOrgID         = "1979710"
+	DefaultAccountNumber = "540155"
+	DefaultUsername      = "jdoe"
+)
+
+// New returns a new identity of the given type populated with values which pass
+// identity.DecodeAndCheckIdentity, false is returned for unknown types.
+func New(t identity.IdentityType) (identity.XRHID, bool) {
+	id := identity.XRHID{
+		Identity: identity.Identity{
+			AccountNumber: DefaultAccountNumber,
+			OrgID:         DefaultOrgID,
+			Internal:      identity.Internal{OrgID: DefaultOrgID},
+			Type:          string(t),
+		},
+		Entitlements: map[string]identity.ServiceDetails{
+			"insights": {IsEntitled: true},
+		},
+	}
+
+	switch t {
+	case identity.TypeUser:
+		id.Identity.AuthType = string(identity.AuthTypeJWT)
+		id.Identity.User = &identity.User{
+			Username:  DefaultUsername,
+			Email:     DefaultUsername + "@example.com",
+			FirstName: "John",
+			LastName:  "Doe",
+			Active:    true,
+			Locale:    "en_US",
+			UserID:    "55555555",
+		}
+	case identity.TypeSystem:
+		id.Identity.AuthType = string(identity.AuthTypeCert)
+		id.Identity.System = &identity.System{
+			CommonName: "4c2b0b5a-0a7e-4b6c-9a2f-3f8d2b1a6e10",
+			CertType:   "system",
+		}
+	case identity.TypeAssociate:
+		id.Identity.AuthType = string(identity.AuthTypeSAML)
+		id.Identity.Associate = &identity.Associate{
+			Role:      []string{"some-ldap-group"},
+			Email:     DefaultUsername + "@redhat.com",
+			GivenName: "John",
+			RHatUUID:  "01234567-89ab-cdef-0123-456789abcdef",
+			Surname:   "Doe",
+		}
+	case identity.TypeX509:
+		id.Identity.AuthType = string(identity.AuthTypeX509)
+		id.Identity.X509 = &identity.X509{
+			SubjectDN: "/O=Red Hat/OU=Insights/CN=service.example.com",
+			IssuerDN:  "/O=Red Hat/OU=prod/CN=Certificate Authority",
+		}
+	case identity.TypeServiceAccount:
+		id.Identity.AuthType = string(identity.AuthTypeJWT)
+		id.Identity.ServiceAccount = &identity.ServiceAccount{
+			ClientId: "b69eaf9e-e6a6-4f9e-805e-02987daddfbd",
+			Username: "service-account-b69eaf9e-e6a6-4f9e-805e-02987daddfbd",
+			UserId:   "5d16465b-c0be-4cf6-a26f-084ebbc5e67d",
+		}
+	default:
+		return identity.XRHID{}, false
+	}
+	return id, true
+}
+
+// Sample returns New with the org ID and username applied when they are not empty,
+// the username is only set on User and ServiceAccount identities.
+func Sample(t identity.IdentityType, orgID, username string) (identity.XRHID, error) {
+	id, ok := New(t)
+	if !ok {
+		return id, fmt.Errorf("unknown identity type %q", t)
+	}
+
+	if orgID != "" {
+		id.Identity.OrgID = orgID
+		id.Identity.Internal.OrgID = orgID
+	}
+	if username != "" {
+		switch {
+		case id.Identity.User != nil:
+			id.Identity.User.Username = username
+		case id.Identity.ServiceAccount != nil:
+			id.Identity.ServiceAccount.Username = username
+		}
+	}
+	return id, nil
+}
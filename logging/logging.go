@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
 	"github.com/redhatinsights/platform-go-middlewares/v2/request_id"
 	"go.uber.org/zap"
 )
@@ -14,20 +15,30 @@ import (
 // and how long it took to return.
 // This is a slightly modified version of the code found here:
 // https://github.com/treastech/logger
+//
+// Requests of cross-account identities are logged with the "cross_access" field, the
+// identity middleware may be placed either before or after the logger.
 func Logger(l *zap.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ctx, crossAccess := identity.TrackCrossAccess(r.Context())
+			r = r.WithContext(ctx)
 
 			t1 := time.Now()
 			defer func() {
-				l.Info("Served",
+				fields := []zap.Field{
 					zap.String("proto", r.Proto),
 					zap.String("path", r.URL.Path),
 					zap.Duration("duration", time.Since(t1)),
 					zap.Int("status", ww.Status()),
 					zap.Int("size", ww.BytesWritten()),
-					zap.String("request_id", request_id.GetReqID(r.Context())))
+					zap.String("request_id", request_id.GetReqID(r.Context())),
+				}
+				if crossAccess() || identity.IsCrossAccess(r.Context()) {
+					fields = append(fields, zap.Bool("cross_access", true))
+				}
+				l.Info("Served", fields...)
 			}()
 
 			next.ServeHTTP(ww, r)
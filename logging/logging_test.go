@@ -0,0 +1,58 @@
+package logging_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity"
+	"github.com/redhatinsights/platform-go-middlewares/v2/identity/identitytest"
+	"github.com/redhatinsights/platform-go-middlewares/v2/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logger", func() {
+	var logs *observer.ObservedLogs
+
+	// serve runs the request through the logger and the identity middleware in the
+	// given order and returns fields of the logged entry
+	serve := func(loggerFirst bool, req *http.Request) map[string]any {
+		core, observed := observer.New(zap.InfoLevel)
+		logs = observed
+		logger := logging.Logger(zap.New(core))
+		enforce := identity.EnforceIdentityWithLogger(nil)
+
+		var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		if loggerFirst {
+			handler = logger(enforce(handler))
+		} else {
+			handler = enforce(logger(handler))
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		Expect(logs.Len()).To(Equal(1))
+		return logs.All()[0].ContextMap()
+	}
+
+	It("should log requests", func() {
+		fields := serve(true, identitytest.User().Request("GET", "/api"))
+		Expect(fields).To(HaveKeyWithValue("path", "/api"))
+		Expect(fields).To(HaveKeyWithValue("status", int64(http.StatusNoContent)))
+		Expect(fields).NotTo(HaveKey("cross_access"))
+	})
+
+	It("should log cross-account access when placed before the identity middleware", func() {
+		fields := serve(true, identitytest.User().CrossAccess(true).Request("GET", "/"))
+		Expect(fields).To(HaveKeyWithValue("cross_access", true))
+	})
+
+	It("should log cross-account access when placed after the identity middleware", func() {
+		fields := serve(false, identitytest.User().CrossAccess(true).Request("GET", "/"))
+		Expect(fields).To(HaveKeyWithValue("cross_access", true))
+	})
+})